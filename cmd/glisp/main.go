@@ -7,10 +7,12 @@ import (
 	"strings"
 
 	"github.com/pmukhin/glisp/cmd/glisp/repl"
+	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/diag"
 	"github.com/pmukhin/glisp/pkg/interpreter"
+	"github.com/pmukhin/glisp/pkg/object"
 	"github.com/pmukhin/glisp/pkg/parser"
 	"github.com/pmukhin/glisp/pkg/scanner"
-	"github.com/pmukhin/glisp/pkg/object"
 )
 
 func main() {
@@ -25,6 +27,8 @@ func main() {
 	switch args[1] {
 	case "--repl", "-r":
 		runner = repl.Main
+	case "--check", "-c":
+		runner = checkFile
 	default:
 		if strings.HasSuffix(args[1], ".glisp") ||
 			strings.HasSuffix(args[1], ".gl") {
@@ -35,25 +39,40 @@ func main() {
 	runner()
 }
 
-func runFile() {
-	filename := os.Args[1]
+// parseFile reads and parses a file, all syntax errors
+// found are reported at once
+func parseFile(filename string) (*ast.Program, string) {
 	bts, err := ioutil.ReadFile(filename)
 
 	if err != nil {
 		exit(err.Error())
 	}
 
-	scn := scanner.New(strings.Trim(string(bts), "\n"))
+	source := string(bts)
+	scn := scanner.NewFile(filename, source)
 	prs := parser.New(scn)
 
 	prg, err := prs.Parse()
 	if err != nil {
-		exit(err.Error())
+		exit(diag.Render(err, source))
+	}
+	return prg, source
+}
+
+// checkFile only parses a file reporting syntax errors
+func checkFile() {
+	if len(os.Args) < 3 {
+		usage()
 	}
+	parseFile(os.Args[2])
+}
+
+func runFile() {
+	prg, source := parseFile(os.Args[1])
 	ctx := object.NewContext()
-	_, err = interpreter.Eval(prg, ctx)
+	_, err := interpreter.Eval(prg, ctx)
 	if err != nil {
-		exit(err.Error())
+		exit(diag.Render(err, source))
 	}
 }
 
@@ -63,6 +82,6 @@ func exit(msg string) {
 }
 
 func usage() {
-	fmt.Println("glisp <file>.glisp | glisp --run")
+	fmt.Println("glisp <file>.glisp | glisp --repl | glisp --check <file>.glisp")
 	os.Exit(0)
 }
@@ -6,10 +6,11 @@ import (
 	"os"
 	"strings"
 
+	"github.com/pmukhin/glisp/pkg/diag"
 	"github.com/pmukhin/glisp/pkg/interpreter"
+	"github.com/pmukhin/glisp/pkg/object"
 	"github.com/pmukhin/glisp/pkg/parser"
 	"github.com/pmukhin/glisp/pkg/scanner"
-	"github.com/pmukhin/glisp/pkg/object"
 )
 
 func Main() {
@@ -25,24 +26,21 @@ func Main() {
 			continue
 		}
 
-		scn := scanner.New(strings.Trim(string(bts), "\n"))
+		line := strings.Trim(string(bts), "\n")
+		scn := scanner.New(line)
 		prs := parser.New(scn)
+		prs.SetMacroLookup(interpreter.MacroLookup(ctx))
 
 		prg, err := prs.Parse()
 		if err != nil {
-			fmt.Println(err.Error())
+			fmt.Println(diag.Render(err, line))
 			continue
 		}
 		res, err := interpreter.Eval(prg, ctx)
 		if err != nil {
-			fmt.Println(err.Error())
+			fmt.Println(diag.Render(err, line))
 			continue
 		}
-		if res == nil {
-			fmt.Println()
-			continue
-		}
-
 		// trim printed output to void duplication newlines
 		fmt.Println(strings.Trim(res.String(), "\n\r"))
 	}
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -11,7 +12,7 @@ import (
 type Type int8
 
 const (
-	FunCall     Type = iota
+	FunCall Type = iota
 	ProgramExpr
 	Expr
 	IdentExpr
@@ -22,6 +23,30 @@ const (
 	ListExpr
 	VectorExpr
 	DefVarExpr
+	DefunExpr
+	LambdaExpr
+	IfExpr
+	CondExpr
+	WhenExpr
+	UnlessExpr
+	AndExpr
+	OrExpr
+	LetExpr
+	SetqExpr
+	WhileExpr
+	DotimesExpr
+	DolistExpr
+	DefmacroExpr
+	MacroCallExpr
+	QuasiQuoteExpr
+	UnquoteExpr
+	UnquoteSplicingExpr
+	KeywordExpr
+	QuoteExpr
+	MapExpr
+	NilExpr
+	BoolExpr
+	BigIntExpr
 )
 
 var type2str = map[Type]string{
@@ -36,6 +61,31 @@ var type2str = map[Type]string{
 	ListExpr:    "ListExpr",
 	VectorExpr:  "VectorExpr",
 	DefVarExpr:  "DefVarExpr",
+	DefunExpr:   "DefunExpr",
+	LambdaExpr:  "LambdaExpr",
+	IfExpr:      "IfExpr",
+	CondExpr:    "CondExpr",
+	WhenExpr:    "WhenExpr",
+	UnlessExpr:  "UnlessExpr",
+	AndExpr:     "AndExpr",
+	OrExpr:      "OrExpr",
+	LetExpr:     "LetExpr",
+	SetqExpr:    "SetqExpr",
+	WhileExpr:   "WhileExpr",
+	DotimesExpr: "DotimesExpr",
+	DolistExpr:  "DolistExpr",
+
+	DefmacroExpr:        "DefmacroExpr",
+	MacroCallExpr:       "MacroCallExpr",
+	QuasiQuoteExpr:      "QuasiQuoteExpr",
+	UnquoteExpr:         "UnquoteExpr",
+	UnquoteSplicingExpr: "UnquoteSplicingExpr",
+	KeywordExpr:         "KeywordExpr",
+	QuoteExpr:           "QuoteExpr",
+	MapExpr:             "MapExpr",
+	NilExpr:             "NilExpr",
+	BoolExpr:            "BoolExpr",
+	BigIntExpr:          "BigIntExpr",
 }
 
 func (t Type) String() string {
@@ -43,7 +93,7 @@ func (t Type) String() string {
 }
 
 type Node interface {
-	Pos() int
+	Pos() token.Position
 	Type() Type
 	String() string
 }
@@ -69,7 +119,7 @@ func (es ExpressionStatement) Type() Type {
 }
 
 // Pos ...
-func (es ExpressionStatement) Pos() int {
+func (es ExpressionStatement) Pos() token.Position {
 	return es.Expression.Pos()
 }
 
@@ -102,7 +152,7 @@ func (fc FunctionCall) String() string {
 }
 
 // Pos ...
-func (fc FunctionCall) Pos() int {
+func (fc FunctionCall) Pos() token.Position {
 	return fc.Token.Pos
 }
 
@@ -113,7 +163,7 @@ type IdentifierExpression struct {
 	Value string
 }
 
-func (id IdentifierExpression) Pos() int { return id.Token.Pos }
+func (id IdentifierExpression) Pos() token.Position { return id.Token.Pos }
 
 func (id IdentifierExpression) Type() Type { return IdentExpr }
 
@@ -129,7 +179,7 @@ type IntegerExpression struct {
 	Value int64
 }
 
-func (ie IntegerExpression) Pos() int {
+func (ie IntegerExpression) Pos() token.Position {
 	return ie.Token.Pos
 }
 
@@ -143,6 +193,30 @@ func (ie IntegerExpression) String() string {
 
 func (ie IntegerExpression) expressionNode() {}
 
+// BigIntegerExpression is an integer literal not fitting in int64
+type BigIntegerExpression struct {
+	Token token.Token
+	Value *big.Int
+}
+
+// Pos ...
+func (bi BigIntegerExpression) Pos() token.Position {
+	return bi.Token.Pos
+}
+
+// Type ...
+func (bi BigIntegerExpression) Type() Type {
+	return BigIntExpr
+}
+
+// String ...
+func (bi BigIntegerExpression) String() string {
+	return bi.Value.String()
+}
+
+// expressionNode ...
+func (bi BigIntegerExpression) expressionNode() {}
+
 // FloatExpression ...
 type FloatExpression struct {
 	Token token.Token
@@ -150,7 +224,7 @@ type FloatExpression struct {
 }
 
 // Pos ...
-func (fe FloatExpression) Pos() int {
+func (fe FloatExpression) Pos() token.Position {
 	return fe.Token.Pos
 }
 
@@ -173,7 +247,7 @@ type StringExpression struct {
 	Value string
 }
 
-func (se StringExpression) Pos() int {
+func (se StringExpression) Pos() token.Position {
 	return se.Token.Pos
 }
 
@@ -193,8 +267,8 @@ type Program struct {
 }
 
 // Pos ...
-func (Program) Pos() int {
-	return 0
+func (Program) Pos() token.Position {
+	return token.Position{}
 }
 
 // Type ...
@@ -207,7 +281,7 @@ type RuneExpression struct {
 	Value rune
 }
 
-func (re RuneExpression) Pos() int {
+func (re RuneExpression) Pos() token.Position {
 	return re.Token.Pos
 }
 
@@ -221,6 +295,74 @@ func (re RuneExpression) String() string {
 
 func (re RuneExpression) expressionNode() {}
 
+// NilExpression is the nil literal
+type NilExpression struct {
+	Token token.Token
+}
+
+// Pos ...
+func (ne NilExpression) Pos() token.Position {
+	return ne.Token.Pos
+}
+
+// Type ...
+func (ne NilExpression) Type() Type {
+	return NilExpr
+}
+
+// String ...
+func (ne NilExpression) String() string {
+	return "nil"
+}
+
+func (ne NilExpression) expressionNode() {}
+
+// BoolExpression is either true or false literal
+type BoolExpression struct {
+	Token token.Token
+	Value bool
+}
+
+// Pos ...
+func (be BoolExpression) Pos() token.Position {
+	return be.Token.Pos
+}
+
+// Type ...
+func (be BoolExpression) Type() Type {
+	return BoolExpr
+}
+
+// String ...
+func (be BoolExpression) String() string {
+	return strconv.FormatBool(be.Value)
+}
+
+func (be BoolExpression) expressionNode() {}
+
+// KeywordExpression is a :name literal, Value is the name
+type KeywordExpression struct {
+	Token token.Token
+	Value string
+}
+
+// Pos ...
+func (ke KeywordExpression) Pos() token.Position {
+	return ke.Token.Pos
+}
+
+// Type ...
+func (ke KeywordExpression) Type() Type {
+	return KeywordExpr
+}
+
+// String ...
+func (ke KeywordExpression) String() string {
+	return ":" + ke.Value
+}
+
+func (ke KeywordExpression) expressionNode() {}
+
 // ListExpression ...
 type ListExpression struct {
 	Token    token.Token
@@ -228,7 +370,7 @@ type ListExpression struct {
 }
 
 // Pos ...
-func (le ListExpression) Pos() int {
+func (le ListExpression) Pos() token.Position {
 	return le.Token.Pos
 }
 
@@ -243,12 +385,41 @@ func (le ListExpression) String() string {
 	for i, el := range le.Elements {
 		strList[i] = el.String()
 	}
-	return "'(" + strings.Join(strList, " ") + ")"
+	return "(" + strings.Join(strList, " ") + ")"
 }
 
 // expressionNode ...
 func (le ListExpression) expressionNode() {}
 
+// MapExpression is a {k v ...} literal, Keys and Values are of the same length
+type MapExpression struct {
+	Token  token.Token
+	Keys   []Expression
+	Values []Expression
+}
+
+// Pos ...
+func (me MapExpression) Pos() token.Position {
+	return me.Token.Pos
+}
+
+// Type ...
+func (me MapExpression) Type() Type {
+	return MapExpr
+}
+
+// String ...
+func (me MapExpression) String() string {
+	strPairs := make([]string, len(me.Keys))
+	for i, key := range me.Keys {
+		strPairs[i] = key.String() + " " + me.Values[i].String()
+	}
+	return "{" + strings.Join(strPairs, " ") + "}"
+}
+
+// expressionNode ...
+func (me MapExpression) expressionNode() {}
+
 // VectorExpression ...
 type VectorExpression struct {
 	Token    token.Token
@@ -256,7 +427,7 @@ type VectorExpression struct {
 }
 
 // VectorExpression ...
-func (ve VectorExpression) Pos() int { return ve.Token.Pos }
+func (ve VectorExpression) Pos() token.Position { return ve.Token.Pos }
 
 // Type ...
 func (ve VectorExpression) Type() Type { return VectorExpr }
@@ -282,7 +453,7 @@ type DefVarExpression struct {
 }
 
 // Pos ...
-func (dve DefVarExpression) Pos() int { return dve.Token.Pos }
+func (dve DefVarExpression) Pos() token.Position { return dve.Token.Pos }
 
 // Type ...
 func (dve DefVarExpression) Type() Type { return DefVarExpr }
@@ -296,6 +467,501 @@ func (dve DefVarExpression) String() string {
 // expressionNode ...
 func (dve DefVarExpression) expressionNode() {}
 
+// DefunExpression ...
+type DefunExpression struct {
+	Token   token.Token
+	Name    *IdentifierExpression
+	Params  []*IdentifierExpression
+	Rest    *IdentifierExpression
+	Comment Expression
+	Body    []Expression
+}
+
+// Pos ...
+func (de DefunExpression) Pos() token.Position { return de.Token.Pos }
+
+// Type ...
+func (de DefunExpression) Type() Type { return DefunExpr }
+
+// String ...
+func (de DefunExpression) String() string {
+	return fmt.Sprintf("(defun %s (%s) %s)", de.Name.String(),
+		joinParams(de.Params, de.Rest), joinExpressions(de.Body))
+}
+
+// expressionNode ...
+func (de DefunExpression) expressionNode() {}
+
+// LambdaExpression ...
+type LambdaExpression struct {
+	Token  token.Token
+	Params []*IdentifierExpression
+	Rest   *IdentifierExpression
+	Body   []Expression
+}
+
+// Pos ...
+func (le LambdaExpression) Pos() token.Position { return le.Token.Pos }
+
+// Type ...
+func (le LambdaExpression) Type() Type { return LambdaExpr }
+
+// String ...
+func (le LambdaExpression) String() string {
+	return fmt.Sprintf("(lambda (%s) %s)", joinParams(le.Params, le.Rest),
+		joinExpressions(le.Body))
+}
+
+// expressionNode ...
+func (le LambdaExpression) expressionNode() {}
+
+// IfExpression ...
+type IfExpression struct {
+	Token       token.Token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+// Pos ...
+func (ie IfExpression) Pos() token.Position { return ie.Token.Pos }
+
+// Type ...
+func (ie IfExpression) Type() Type { return IfExpr }
+
+// String ...
+func (ie IfExpression) String() string {
+	if ie.Alternative == nil {
+		return fmt.Sprintf("(if %s %s)", ie.Condition.String(), ie.Consequence.String())
+	}
+	return fmt.Sprintf("(if %s %s %s)", ie.Condition.String(),
+		ie.Consequence.String(), ie.Alternative.String())
+}
+
+// expressionNode ...
+func (ie IfExpression) expressionNode() {}
+
+// CondClause is a single `(test body...)` clause of cond,
+// Condition is nil for the `else` clause
+type CondClause struct {
+	Condition Expression
+	Body      []Expression
+}
+
+// String ...
+func (cc CondClause) String() string {
+	if cc.Condition == nil {
+		return fmt.Sprintf("(else %s)", joinExpressions(cc.Body))
+	}
+	return fmt.Sprintf("(%s %s)", cc.Condition.String(), joinExpressions(cc.Body))
+}
+
+// CondExpression ...
+type CondExpression struct {
+	Token   token.Token
+	Clauses []*CondClause
+}
+
+// Pos ...
+func (ce CondExpression) Pos() token.Position { return ce.Token.Pos }
+
+// Type ...
+func (ce CondExpression) Type() Type { return CondExpr }
+
+// String ...
+func (ce CondExpression) String() string {
+	clauses := make([]string, len(ce.Clauses))
+	for i, c := range ce.Clauses {
+		clauses[i] = c.String()
+	}
+	return "(cond " + strings.Join(clauses, " ") + ")"
+}
+
+// expressionNode ...
+func (ce CondExpression) expressionNode() {}
+
+// WhenExpression ...
+type WhenExpression struct {
+	Token     token.Token
+	Condition Expression
+	Body      []Expression
+}
+
+// Pos ...
+func (we WhenExpression) Pos() token.Position { return we.Token.Pos }
+
+// Type ...
+func (we WhenExpression) Type() Type { return WhenExpr }
+
+// String ...
+func (we WhenExpression) String() string {
+	return fmt.Sprintf("(when %s %s)", we.Condition.String(), joinExpressions(we.Body))
+}
+
+// expressionNode ...
+func (we WhenExpression) expressionNode() {}
+
+// UnlessExpression ...
+type UnlessExpression struct {
+	Token     token.Token
+	Condition Expression
+	Body      []Expression
+}
+
+// Pos ...
+func (ue UnlessExpression) Pos() token.Position { return ue.Token.Pos }
+
+// Type ...
+func (ue UnlessExpression) Type() Type { return UnlessExpr }
+
+// String ...
+func (ue UnlessExpression) String() string {
+	return fmt.Sprintf("(unless %s %s)", ue.Condition.String(), joinExpressions(ue.Body))
+}
+
+// expressionNode ...
+func (ue UnlessExpression) expressionNode() {}
+
+// AndExpression ...
+type AndExpression struct {
+	Token token.Token
+	Args  []Expression
+}
+
+// Pos ...
+func (ae AndExpression) Pos() token.Position { return ae.Token.Pos }
+
+// Type ...
+func (ae AndExpression) Type() Type { return AndExpr }
+
+// String ...
+func (ae AndExpression) String() string {
+	return "(and " + joinExpressions(ae.Args) + ")"
+}
+
+// expressionNode ...
+func (ae AndExpression) expressionNode() {}
+
+// OrExpression ...
+type OrExpression struct {
+	Token token.Token
+	Args  []Expression
+}
+
+// Pos ...
+func (oe OrExpression) Pos() token.Position { return oe.Token.Pos }
+
+// Type ...
+func (oe OrExpression) Type() Type { return OrExpr }
+
+// String ...
+func (oe OrExpression) String() string {
+	return "(or " + joinExpressions(oe.Args) + ")"
+}
+
+// expressionNode ...
+func (oe OrExpression) expressionNode() {}
+
+// LetBinding is a single `(name value)` binding of let
+type LetBinding struct {
+	Name  *IdentifierExpression
+	Value Expression
+}
+
+// String ...
+func (lb LetBinding) String() string {
+	return fmt.Sprintf("(%s %s)", lb.Name.String(), lb.Value.String())
+}
+
+// LetExpression is both let and let*, the latter has Sequential set
+type LetExpression struct {
+	Token      token.Token
+	Bindings   []*LetBinding
+	Body       []Expression
+	Sequential bool
+}
+
+// Pos ...
+func (le LetExpression) Pos() token.Position { return le.Token.Pos }
+
+// Type ...
+func (le LetExpression) Type() Type { return LetExpr }
+
+// String ...
+func (le LetExpression) String() string {
+	bindings := make([]string, len(le.Bindings))
+	for i, b := range le.Bindings {
+		bindings[i] = b.String()
+	}
+	name := "let"
+	if le.Sequential {
+		name = "let*"
+	}
+	return fmt.Sprintf("(%s (%s) %s)", name, strings.Join(bindings, " "),
+		joinExpressions(le.Body))
+}
+
+// expressionNode ...
+func (le LetExpression) expressionNode() {}
+
+// SetqExpression is both setq and set!, Names[i] is assigned Values[i]
+type SetqExpression struct {
+	Token  token.Token
+	Names  []*IdentifierExpression
+	Values []Expression
+}
+
+// Pos ...
+func (se SetqExpression) Pos() token.Position { return se.Token.Pos }
+
+// Type ...
+func (se SetqExpression) Type() Type { return SetqExpr }
+
+// String ...
+func (se SetqExpression) String() string {
+	pairs := make([]string, len(se.Names))
+	for i, name := range se.Names {
+		pairs[i] = name.String() + " " + se.Values[i].String()
+	}
+	return fmt.Sprintf("(%s %s)", se.Token.Literal, strings.Join(pairs, " "))
+}
+
+// expressionNode ...
+func (se SetqExpression) expressionNode() {}
+
+// WhileExpression ...
+type WhileExpression struct {
+	Token     token.Token
+	Condition Expression
+	Body      []Expression
+}
+
+// Pos ...
+func (we WhileExpression) Pos() token.Position { return we.Token.Pos }
+
+// Type ...
+func (we WhileExpression) Type() Type { return WhileExpr }
+
+// String ...
+func (we WhileExpression) String() string {
+	return fmt.Sprintf("(while %s %s)", we.Condition.String(), joinExpressions(we.Body))
+}
+
+// expressionNode ...
+func (we WhileExpression) expressionNode() {}
+
+// DotimesExpression is `(dotimes (var count result) body...)`,
+// Result is optional
+type DotimesExpression struct {
+	Token  token.Token
+	Var    *IdentifierExpression
+	Count  Expression
+	Result Expression
+	Body   []Expression
+}
+
+// Pos ...
+func (de DotimesExpression) Pos() token.Position { return de.Token.Pos }
+
+// Type ...
+func (de DotimesExpression) Type() Type { return DotimesExpr }
+
+// String ...
+func (de DotimesExpression) String() string {
+	return fmt.Sprintf("(dotimes %s %s)", loopSpecString(de.Var, de.Count, de.Result),
+		joinExpressions(de.Body))
+}
+
+// expressionNode ...
+func (de DotimesExpression) expressionNode() {}
+
+// DolistExpression is `(dolist (var list result) body...)`,
+// Result is optional
+type DolistExpression struct {
+	Token  token.Token
+	Var    *IdentifierExpression
+	List   Expression
+	Result Expression
+	Body   []Expression
+}
+
+// Pos ...
+func (de DolistExpression) Pos() token.Position { return de.Token.Pos }
+
+// Type ...
+func (de DolistExpression) Type() Type { return DolistExpr }
+
+// String ...
+func (de DolistExpression) String() string {
+	return fmt.Sprintf("(dolist %s %s)", loopSpecString(de.Var, de.List, de.Result),
+		joinExpressions(de.Body))
+}
+
+// expressionNode ...
+func (de DolistExpression) expressionNode() {}
+
+func loopSpecString(v *IdentifierExpression, value, result Expression) string {
+	if result == nil {
+		return fmt.Sprintf("(%s %s)", v.String(), value.String())
+	}
+	return fmt.Sprintf("(%s %s %s)", v.String(), value.String(), result.String())
+}
+
+// DefmacroExpression ...
+type DefmacroExpression struct {
+	Token  token.Token
+	Name   *IdentifierExpression
+	Params []*IdentifierExpression
+	Rest   *IdentifierExpression
+	Body   []Expression
+}
+
+// Pos ...
+func (de DefmacroExpression) Pos() token.Position { return de.Token.Pos }
+
+// Type ...
+func (de DefmacroExpression) Type() Type { return DefmacroExpr }
+
+// String ...
+func (de DefmacroExpression) String() string {
+	return fmt.Sprintf("(defmacro %s (%s) %s)", de.Name.String(),
+		joinParams(de.Params, de.Rest), joinExpressions(de.Body))
+}
+
+// expressionNode ...
+func (de DefmacroExpression) expressionNode() {}
+
+// MacroCallExpression is a call of a user macro,
+// its args are unevaluated forms
+type MacroCallExpression struct {
+	Token token.Token
+	Name  *IdentifierExpression
+	Args  []Expression
+	// Expansion is the form the call expands to,
+	// it's set when the call is evaluated first time
+	Expansion Expression
+}
+
+// Pos ...
+func (mce MacroCallExpression) Pos() token.Position { return mce.Token.Pos }
+
+// Type ...
+func (mce MacroCallExpression) Type() Type { return MacroCallExpr }
+
+// String ...
+func (mce MacroCallExpression) String() string {
+	return fmt.Sprintf("(%s %s)", mce.Name.String(), joinExpressions(mce.Args))
+}
+
+// expressionNode ...
+func (mce MacroCallExpression) expressionNode() {}
+
+// QuoteExpression is 'datum or (quote datum), the datum is taken as it is
+type QuoteExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+// Pos ...
+func (qe QuoteExpression) Pos() token.Position { return qe.Token.Pos }
+
+// Type ...
+func (qe QuoteExpression) Type() Type { return QuoteExpr }
+
+// String ...
+func (qe QuoteExpression) String() string {
+	return "'" + qe.Value.String()
+}
+
+// expressionNode ...
+func (qe QuoteExpression) expressionNode() {}
+
+// QuasiQuoteExpression is a template built from `datum
+type QuasiQuoteExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+// Pos ...
+func (qqe QuasiQuoteExpression) Pos() token.Position { return qqe.Token.Pos }
+
+// Type ...
+func (qqe QuasiQuoteExpression) Type() Type { return QuasiQuoteExpr }
+
+// String ...
+func (qqe QuasiQuoteExpression) String() string {
+	return "`" + qqe.Value.String()
+}
+
+// expressionNode ...
+func (qqe QuasiQuoteExpression) expressionNode() {}
+
+// UnquoteExpression is ,expr inside of a quasiquote
+type UnquoteExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+// Pos ...
+func (ue UnquoteExpression) Pos() token.Position { return ue.Token.Pos }
+
+// Type ...
+func (ue UnquoteExpression) Type() Type { return UnquoteExpr }
+
+// String ...
+func (ue UnquoteExpression) String() string {
+	return "," + ue.Value.String()
+}
+
+// expressionNode ...
+func (ue UnquoteExpression) expressionNode() {}
+
+// UnquoteSplicingExpression is ,@expr inside of a quasiquote
+type UnquoteSplicingExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+// Pos ...
+func (use UnquoteSplicingExpression) Pos() token.Position { return use.Token.Pos }
+
+// Type ...
+func (use UnquoteSplicingExpression) Type() Type { return UnquoteSplicingExpr }
+
+// String ...
+func (use UnquoteSplicingExpression) String() string {
+	return ",@" + use.Value.String()
+}
+
+// expressionNode ...
+func (use UnquoteSplicingExpression) expressionNode() {}
+
+func joinParams(params []*IdentifierExpression, rest *IdentifierExpression) string {
+	if rest == nil {
+		return joinIdentifiers(params)
+	}
+	if len(params) == 0 {
+		return "&rest " + rest.String()
+	}
+	return joinIdentifiers(params) + " &rest " + rest.String()
+}
+
+func joinIdentifiers(ids []*IdentifierExpression) string {
+	strList := make([]string, len(ids))
+	for i, id := range ids {
+		strList[i] = id.String()
+	}
+	return strings.Join(strList, " ")
+}
+
+func joinExpressions(exprs []Expression) string {
+	strList := make([]string, len(exprs))
+	for i, expr := range exprs {
+		strList[i] = expr.String()
+	}
+	return strings.Join(strList, " ")
+}
+
 // String ...
 func (p Program) String() string {
 	stmts := make([]string, len(p.Statements))
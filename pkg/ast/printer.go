@@ -1,8 +1,8 @@
 package ast
 
 import (
-	"strings"
 	"fmt"
+	"strings"
 )
 
 var astType2printer map[Type]func(node Node) string
@@ -12,20 +12,218 @@ func init() {
 		ProgramExpr: printProgram,
 		Expr:        printExpr,
 		IntExpr:     printInt,
+		BigIntExpr:  printBigInt,
 		StringExpr:  printStr,
+		RuneExpr:    printRune,
+		KeywordExpr: printKeyword,
+		NilExpr:     printNil,
+		BoolExpr:    printBool,
 		IdentExpr:   printIdent,
 		FloatExpr:   printFloat,
 		DefVarExpr:  printDefVar,
 		ListExpr:    printList,
+		MapExpr:     printMap,
+		DefunExpr:   printDefun,
+		LambdaExpr:  printLambda,
+		IfExpr:      printIf,
+		CondExpr:    printCond,
+		WhenExpr:    printWhen,
+		UnlessExpr:  printUnless,
+		AndExpr:     printAnd,
+		OrExpr:      printOr,
+		LetExpr:     printLet,
+		SetqExpr:    printSetq,
+		WhileExpr:   printWhile,
+		DotimesExpr: printDotimes,
+		DolistExpr:  printDolist,
+
+		DefmacroExpr:        printDefmacro,
+		MacroCallExpr:       printMacroCall,
+		QuoteExpr:           printQuote,
+		QuasiQuoteExpr:      printQuasiQuote,
+		UnquoteExpr:         printUnquote,
+		UnquoteSplicingExpr: printUnquoteSplicing,
 	}
 }
 
 func printDefVar(node Node) string {
 	defVar := node.(*DefVarExpression)
-	return fmt.Sprintf("<ast.DefVarExpr> pos: %d value: %s comment: %s", defVar.Pos(),
+	return fmt.Sprintf("<ast.DefVarExpr> pos: %s value: %s comment: %s", defVar.Pos(),
 		Print(defVar.Value), defVar.Comment.String())
 }
 
+func printDefun(node Node) string {
+	defun := node.(*DefunExpression)
+	return fmt.Sprintf("<ast.DefunExpr pos: %s name: %s params: [%s] body: [%s]>", defun.Pos(),
+		defun.Name.Value, printIdentifiers(defun.Params), printExpressions(defun.Body))
+}
+
+func printLambda(node Node) string {
+	lambda := node.(*LambdaExpression)
+	return fmt.Sprintf("<ast.LambdaExpr pos: %s params: [%s] body: [%s]>", lambda.Pos(),
+		printIdentifiers(lambda.Params), printExpressions(lambda.Body))
+}
+
+func printIf(node Node) string {
+	ifExpr := node.(*IfExpression)
+	alternative := "nil"
+	if ifExpr.Alternative != nil {
+		alternative = Print(ifExpr.Alternative)
+	}
+	return fmt.Sprintf("<ast.IfExpr pos: %s condition: %s then: %s else: %s>", ifExpr.Pos(),
+		Print(ifExpr.Condition), Print(ifExpr.Consequence), alternative)
+}
+
+func printCond(node Node) string {
+	condExpr := node.(*CondExpression)
+	clauses := make([]string, 0, len(condExpr.Clauses))
+	for _, c := range condExpr.Clauses {
+		condition := "else"
+		if c.Condition != nil {
+			condition = Print(c.Condition)
+		}
+		clauses = append(clauses, fmt.Sprintf("<clause condition: %s body: [%s]>",
+			condition, printExpressions(c.Body)))
+	}
+	return fmt.Sprintf("<ast.CondExpr pos: %s clauses: [%s]>", condExpr.Pos(),
+		strings.Join(clauses, ", "))
+}
+
+func printWhen(node Node) string {
+	whenExpr := node.(*WhenExpression)
+	return fmt.Sprintf("<ast.WhenExpr pos: %s condition: %s body: [%s]>", whenExpr.Pos(),
+		Print(whenExpr.Condition), printExpressions(whenExpr.Body))
+}
+
+func printUnless(node Node) string {
+	unlessExpr := node.(*UnlessExpression)
+	return fmt.Sprintf("<ast.UnlessExpr pos: %s condition: %s body: [%s]>", unlessExpr.Pos(),
+		Print(unlessExpr.Condition), printExpressions(unlessExpr.Body))
+}
+
+func printAnd(node Node) string {
+	andExpr := node.(*AndExpression)
+	return fmt.Sprintf("<ast.AndExpr pos: %s args: [%s]>", andExpr.Pos(),
+		printExpressions(andExpr.Args))
+}
+
+func printOr(node Node) string {
+	orExpr := node.(*OrExpression)
+	return fmt.Sprintf("<ast.OrExpr pos: %s args: [%s]>", orExpr.Pos(),
+		printExpressions(orExpr.Args))
+}
+
+func printLet(node Node) string {
+	letExpr := node.(*LetExpression)
+	bindings := make([]string, 0, len(letExpr.Bindings))
+	for _, b := range letExpr.Bindings {
+		bindings = append(bindings, fmt.Sprintf("<binding name: %s value: %s>",
+			b.Name.Value, Print(b.Value)))
+	}
+	return fmt.Sprintf("<ast.LetExpr pos: %s sequential: %t bindings: [%s] body: [%s]>",
+		letExpr.Pos(), letExpr.Sequential, strings.Join(bindings, ", "),
+		printExpressions(letExpr.Body))
+}
+
+func printSetq(node Node) string {
+	setqExpr := node.(*SetqExpression)
+	return fmt.Sprintf("<ast.SetqExpr pos: %s names: [%s] values: [%s]>", setqExpr.Pos(),
+		printIdentifiers(setqExpr.Names), printExpressions(setqExpr.Values))
+}
+
+func printWhile(node Node) string {
+	whileExpr := node.(*WhileExpression)
+	return fmt.Sprintf("<ast.WhileExpr pos: %s condition: %s body: [%s]>", whileExpr.Pos(),
+		Print(whileExpr.Condition), printExpressions(whileExpr.Body))
+}
+
+func printDotimes(node Node) string {
+	dotimesExpr := node.(*DotimesExpression)
+	return fmt.Sprintf("<ast.DotimesExpr pos: %s var: %s count: %s result: %s body: [%s]>",
+		dotimesExpr.Pos(), dotimesExpr.Var.Value, Print(dotimesExpr.Count),
+		printOptional(dotimesExpr.Result), printExpressions(dotimesExpr.Body))
+}
+
+func printDolist(node Node) string {
+	dolistExpr := node.(*DolistExpression)
+	return fmt.Sprintf("<ast.DolistExpr pos: %s var: %s list: %s result: %s body: [%s]>",
+		dolistExpr.Pos(), dolistExpr.Var.Value, Print(dolistExpr.List),
+		printOptional(dolistExpr.Result), printExpressions(dolistExpr.Body))
+}
+
+func printDefmacro(node Node) string {
+	defmacro := node.(*DefmacroExpression)
+	params := printIdentifiers(defmacro.Params)
+	if defmacro.Rest != nil {
+		params += " &rest " + defmacro.Rest.Value
+	}
+	return fmt.Sprintf("<ast.DefmacroExpr pos: %s name: %s params: [%s] body: [%s]>",
+		defmacro.Pos(), defmacro.Name.Value, params, printExpressions(defmacro.Body))
+}
+
+func printMacroCall(node Node) string {
+	macroCall := node.(*MacroCallExpression)
+	return fmt.Sprintf("<ast.MacroCallExpr pos: %s name: %s args: [%s]>", macroCall.Pos(),
+		macroCall.Name.Value, printExpressions(macroCall.Args))
+}
+
+func printQuote(node Node) string {
+	quote := node.(*QuoteExpression)
+	return fmt.Sprintf("<ast.QuoteExpr pos: %s value: %s>", quote.Pos(), Print(quote.Value))
+}
+
+func printQuasiQuote(node Node) string {
+	quasiQuote := node.(*QuasiQuoteExpression)
+	return fmt.Sprintf("<ast.QuasiQuoteExpr pos: %s value: %s>", quasiQuote.Pos(),
+		Print(quasiQuote.Value))
+}
+
+func printUnquote(node Node) string {
+	unquote := node.(*UnquoteExpression)
+	return fmt.Sprintf("<ast.UnquoteExpr pos: %s value: %s>", unquote.Pos(),
+		Print(unquote.Value))
+}
+
+func printUnquoteSplicing(node Node) string {
+	unquote := node.(*UnquoteSplicingExpression)
+	return fmt.Sprintf("<ast.UnquoteSplicingExpr pos: %s value: %s>", unquote.Pos(),
+		Print(unquote.Value))
+}
+
+func printOptional(node Node) string {
+	if node == nil {
+		return "nil"
+	}
+	return Print(node)
+}
+
+func printIdentifiers(ids []*IdentifierExpression) string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.Value)
+	}
+	return strings.Join(values, ", ")
+}
+
+func printExpressions(exprs []Expression) string {
+	values := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		values = append(values, Print(expr))
+	}
+	return strings.Join(values, ", ")
+}
+
+func printMap(node Node) string {
+	mapExpr := node.(*MapExpression)
+	pairs := make([]string, 0, len(mapExpr.Keys))
+	for i, key := range mapExpr.Keys {
+		pairs = append(pairs, Print(key)+": "+Print(mapExpr.Values[i]))
+	}
+
+	return fmt.Sprintf("<ast.MapExpr pos: %s value: {%s}>", mapExpr.Pos(),
+		strings.Join(pairs, ", "))
+}
+
 func printList(node Node) string {
 	listExpr := node.(*ListExpression)
 	values := make([]string, 0, len(listExpr.Elements))
@@ -33,28 +231,52 @@ func printList(node Node) string {
 		values = append(values, Print(el))
 	}
 
-	return fmt.Sprintf("<ast.ListExpr pos: %d value: [%s]>", listExpr.Pos(),
+	return fmt.Sprintf("<ast.ListExpr pos: %s value: [%s]>", listExpr.Pos(),
 		strings.Join(values, ", "))
 }
 
 func printIdent(node Node) string {
 	astStr := node.(*IdentifierExpression)
-	return fmt.Sprintf("<ast.IdentExpr pos: %d value: %s>", astStr.Pos(), astStr.Value)
+	return fmt.Sprintf("<ast.IdentExpr pos: %s value: %s>", astStr.Pos(), astStr.Value)
 }
 
 func printStr(node Node) string {
 	astStr := node.(*StringExpression)
-	return fmt.Sprintf("<ast.StringExpr pos: %d value: %s>", astStr.Pos(), astStr.Value)
+	return fmt.Sprintf("<ast.StringExpr pos: %s value: %s>", astStr.Pos(), astStr.Value)
+}
+
+func printRune(node Node) string {
+	astRune := node.(*RuneExpression)
+	return fmt.Sprintf("<ast.RuneExpr pos: %s value: %q>", astRune.Pos(), astRune.Value)
+}
+
+func printNil(node Node) string {
+	return fmt.Sprintf("<ast.NilExpr pos: %s>", node.Pos())
+}
+
+func printBool(node Node) string {
+	astBool := node.(*BoolExpression)
+	return fmt.Sprintf("<ast.BoolExpr pos: %s value: %t>", astBool.Pos(), astBool.Value)
+}
+
+func printKeyword(node Node) string {
+	astKeyword := node.(*KeywordExpression)
+	return fmt.Sprintf("<ast.KeywordExpr pos: %s value: %s>", astKeyword.Pos(), astKeyword.Value)
 }
 
 func printFloat(node Node) string {
 	astFloat := node.(*FloatExpression)
-	return fmt.Sprintf("<ast.FloatExpr pos: %d value: %f>", astFloat.Pos(), astFloat.Value)
+	return fmt.Sprintf("<ast.FloatExpr pos: %s value: %f>", astFloat.Pos(), astFloat.Value)
 }
 
 func printInt(node Node) string {
 	astInt := node.(*IntegerExpression)
-	return fmt.Sprintf("<ast.IntExpr pos: %d value: %d>", astInt.Pos(), astInt.Value)
+	return fmt.Sprintf("<ast.IntExpr pos: %s value: %d>", astInt.Pos(), astInt.Value)
+}
+
+func printBigInt(node Node) string {
+	astBigInt := node.(*BigIntegerExpression)
+	return fmt.Sprintf("<ast.BigIntExpr pos: %s value: %s>", astBigInt.Pos(), astBigInt.Value)
 }
 
 func printExpr(node Node) string {
@@ -0,0 +1,131 @@
+package diag
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/token"
+)
+
+// Kind is a kind of an error
+type Kind int8
+
+const (
+	Runtime Kind = iota
+	Syntax
+	Type
+	Arity
+	Unbound
+)
+
+var kind2str = map[Kind]string{
+	Runtime: "runtime error",
+	Syntax:  "syntax error",
+	Type:    "type error",
+	Arity:   "arity error",
+	Unbound: "unbound name",
+}
+
+func (k Kind) String() string {
+	return kind2str[k]
+}
+
+// Error is an error found in glisp source either
+// while parsing or while evaluating it
+type Error struct {
+	Kind Kind
+	Pos  token.Position
+	// Node is the offending node, it's nil for syntax errors
+	Node ast.Node
+	Msg  string
+}
+
+// Error renders the error as file:line:column: message
+func (e *Error) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// List is a list of errors reported at once,
+// e.g. all syntax errors found in a file
+type List []*Error
+
+// Error renders every error of the list on its own line
+func (l List) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Errorf makes an error of a given kind with no position known yet
+func Errorf(kind Kind, format string, a ...interface{}) *Error {
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, a...)}
+}
+
+// At makes an error of a given kind at a given position
+func At(pos token.Position, kind Kind, format string, a ...interface{}) *Error {
+	return &Error{Kind: kind, Pos: pos, Msg: fmt.Sprintf(format, a...)}
+}
+
+// Locate puts node and its position into err unless err knows its
+// position already, errors of other types become runtime errors
+func Locate(err error, node ast.Node) error {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Kind: Runtime, Msg: err.Error()}
+	} else if e.Pos.IsValid() {
+		return e
+	} else {
+		located := *e
+		e = &located
+	}
+
+	if node != nil && node.Pos().IsValid() {
+		e.Node = node
+		e.Pos = node.Pos()
+	}
+	return e
+}
+
+// Render renders err with the line of source it's found at
+// and a caret under the column, errors not having a position
+// are rendered as they are
+func Render(err error, source string) string {
+	if l, ok := err.(List); ok {
+		rendered := make([]string, len(l))
+		for i, e := range l {
+			rendered[i] = Render(e, source)
+		}
+		return strings.Join(rendered, "\n")
+	}
+
+	e, ok := err.(*Error)
+	if !ok || !e.Pos.IsValid() {
+		return err.Error()
+	}
+
+	header := fmt.Sprintf("%s: %s: %s", e.Pos, e.Kind, e.Msg)
+	lines := strings.Split(source, "\n")
+	if e.Pos.Line > len(lines) {
+		return header
+	}
+	line := []rune(strings.TrimRight(lines[e.Pos.Line-1], "\r"))
+
+	// keep tabs so that the caret is aligned the same way the line is
+	caret := make([]rune, 0, e.Pos.Column)
+	for i := 0; i < e.Pos.Column-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			caret = append(caret, '\t')
+		} else {
+			caret = append(caret, ' ')
+		}
+	}
+	caret = append(caret, '^')
+
+	return fmt.Sprintf("%s\n    %s\n    %s", header, string(line), string(caret))
+}
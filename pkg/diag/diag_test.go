@@ -0,0 +1,68 @@
+package diag
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/token"
+)
+
+func TestRender(t *testing.T) {
+	source := "(defvar a 1)\n\t(print (+ a \"b\"))"
+	err := At(token.Position{Filename: "a.gl", Offset: 21, Line: 2, Column: 9},
+		Type, "wrong type")
+
+	expected := "a.gl:2:9: type error: wrong type\n" +
+		"    \t(print (+ a \"b\"))\n" +
+		"    \t       ^"
+	if res := Render(err, source); res != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, res)
+	}
+}
+
+func TestRender_NoPosition(t *testing.T) {
+	if res := Render(errors.New("boom"), "(boom)"); res != "boom" {
+		t.Errorf("expected plain message, got %q", res)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	pos := token.Position{Offset: 3, Line: 1, Column: 4}
+	node := &ast.IdentifierExpression{Token: token.New(token.Identifier, pos, "x"), Value: "x"}
+
+	located := Locate(Errorf(Unbound, "undefined variable x"), node).(*Error)
+	if located.Pos != pos || located.Node != node || located.Kind != Unbound {
+		t.Errorf("expected unbound error at %s, got %#v", pos, located)
+	}
+
+	outer := &ast.IdentifierExpression{Token: token.New(token.Identifier, token.Position{Line: 1, Column: 1}, "y")}
+	if Locate(located, outer).(*Error).Node != node {
+		t.Error("expected the innermost node to be kept")
+	}
+
+	if wrapped := Locate(errors.New("boom"), node).(*Error); wrapped.Kind != Runtime {
+		t.Errorf("expected runtime error, got %s", wrapped.Kind)
+	}
+}
+
+func TestRender_List(t *testing.T) {
+	source := "(a\nb)"
+	err := List{
+		At(token.Position{Offset: 1, Line: 1, Column: 2}, Syntax, "first"),
+		At(token.Position{Offset: 3, Line: 2, Column: 1}, Syntax, "second"),
+	}
+
+	expected := "1:2: syntax error: first\n" +
+		"    (a\n" +
+		"     ^\n" +
+		"2:1: syntax error: second\n" +
+		"    b)\n" +
+		"    ^"
+	if res := Render(err, source); res != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, res)
+	}
+	if err.Error() != "1:2: first\n2:1: second" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
@@ -1,217 +1,236 @@
 package interpreter
 
 import (
+	"math"
+	"math/big"
 	"strings"
 
+	"github.com/pmukhin/glisp/pkg/diag"
 	"github.com/pmukhin/glisp/pkg/object"
 )
 
-func mul(args ...object.Object) (object.Object, error) {
+// numericOp is an arithmetic operation folded over its args. Ints are
+// computed in int64 while they don't overflow, exact numbers are
+// computed as rationals otherwise and any float makes the result a float
+type numericOp struct {
+	name string
+	// ints is false if the result doesn't fit in int64 or is not an integer
+	ints   func(a, b int64) (int64, bool)
+	rats   func(a, b *big.Rat) (*big.Rat, error)
+	floats func(a, b float64) float64
+}
+
+var (
+	addOp = numericOp{
+		name: "__add__",
+		ints: func(a, b int64) (int64, bool) {
+			c := a + b
+			return c, (c >= a) == (b >= 0)
+		},
+		rats:   func(a, b *big.Rat) (*big.Rat, error) { return new(big.Rat).Add(a, b), nil },
+		floats: func(a, b float64) float64 { return a + b },
+	}
+	subOp = numericOp{
+		name: "__sub__",
+		ints: func(a, b int64) (int64, bool) {
+			c := a - b
+			return c, (c <= a) == (b >= 0)
+		},
+		rats:   func(a, b *big.Rat) (*big.Rat, error) { return new(big.Rat).Sub(a, b), nil },
+		floats: func(a, b float64) float64 { return a - b },
+	}
+	mulOp = numericOp{
+		name: "__mul__",
+		ints: func(a, b int64) (int64, bool) {
+			if a == 0 || b == 0 {
+				return 0, true
+			}
+			c := a * b
+			return c, c/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+		},
+		rats:   func(a, b *big.Rat) (*big.Rat, error) { return new(big.Rat).Mul(a, b), nil },
+		floats: func(a, b float64) float64 { return a * b },
+	}
+	divOp = numericOp{
+		name: "__div__",
+		ints: func(a, b int64) (int64, bool) {
+			if b == 0 || a%b != 0 || (a == math.MinInt64 && b == -1) {
+				return 0, false
+			}
+			return a / b, true
+		},
+		rats: func(a, b *big.Rat) (*big.Rat, error) {
+			if b.Sign() == 0 {
+				return nil, diag.Errorf(diag.Runtime, "__div__: division by zero")
+			}
+			return new(big.Rat).Quo(a, b), nil
+		},
+		floats: func(a, b float64) float64 { return a / b },
+	}
+)
+
+// arith is the dispatch shared by all arithmetic builtins
+func arith(op numericOp, args []object.Object) (object.Object, error) {
 	if len(args) < 2 {
-		return nil, makeArgsLenErr("__mul__", 2, len(args))
+		return nil, makeArgsLenErr(op.name, 2, len(args))
+	}
+	if !isNumber(args[0]) {
+		return nil, makeFunNotDefErr(op.name, args[0].Type())
 	}
-	firstType := args[0].Type()
-	switch firstType {
-	// switch arg types...
-	case object.TInt:
-		intArgs, err := extractIntArgs(args)
-		if err != nil {
-			return nil, err
-		}
 
-		return iMul(intArgs...)
-	case object.TFloat:
-		floatArgs, err := extractFloatArgs(args)
-		if err != nil {
+	acc := args[0]
+	for i := 1; i < len(args); i++ {
+		var err error
+		if acc, err = op.apply(i, acc, args[i]); err != nil {
 			return nil, err
 		}
-		return fMul(floatArgs...)
-	case object.TString:
-		strToRep, ok := args[0].(*object.String)
-		if !ok {
-			return nil, makeUnexpectedTypeErr("__mul__", 0,
-				object.TString, args[0].Type())
+	}
+	return acc, nil
+}
+
+// apply applies the operation to a number and a positional argument
+func (op numericOp) apply(pos int, a, b object.Object) (object.Object, error) {
+	if !isNumber(b) {
+		return nil, makeUnexpectedTypeErr(op.name, pos, a.Type(), b.Type())
+	}
+
+	ai, aIsInt := a.(*object.Int)
+	bi, bIsInt := b.(*object.Int)
+	if aIsInt && bIsInt {
+		if v, ok := op.ints(ai.Value, bi.Value); ok {
+			return &object.Int{Value: v}, nil
 		}
-		intArgs, err := extractIntArgs(args[1:])
+	}
+	if isExact(a) && isExact(b) {
+		v, err := op.rats(toRat(a), toRat(b))
 		if err != nil {
 			return nil, err
 		}
-		return sMul(strToRep, intArgs...)
-	default:
-		return nil, makeFunNotDefErr("__mul__", firstType)
+		return object.NewRational(v), nil
 	}
+	return &object.Float{Value: op.floats(toFloat(a), toFloat(b))}, nil
 }
 
-func sMul(strToRep *object.String, muls ...int64) (object.Object, error) {
-	value := strToRep.Value
-	for _, m := range muls {
-		value = strings.Repeat(value, int(m))
+func isNumber(o object.Object) bool {
+	return isExact(o) || o.Type() == object.TFloat
+}
+
+// isExact tells if a number is an integer or a ratio
+func isExact(o object.Object) bool {
+	switch o.(type) {
+	case *object.Int, *object.BigInt, *object.Ratio:
+		return true
+	default:
+		return false
 	}
-	return &object.String{Value: value}, nil
 }
 
-func fMul(args ...float64) (object.Object, error) {
-	ret := args[0]
-	for _, v := range args[1:] {
-		ret *= v
+// toRat converts an exact number to *big.Rat
+func toRat(o object.Object) *big.Rat {
+	switch v := o.(type) {
+	case *object.Int:
+		return new(big.Rat).SetInt64(v.Value)
+	case *object.BigInt:
+		return new(big.Rat).SetInt(v.Value)
+	case *object.Ratio:
+		return v.Value
+	default:
+		return new(big.Rat)
 	}
-	return &object.Float{Value: ret}, nil
 }
 
-func iMul(args ...int64) (object.Object, error) {
-	ret := args[0]
-	for _, v := range args[1:] {
-		ret *= v
+// toFloat converts a number to float64
+func toFloat(o object.Object) float64 {
+	switch v := o.(type) {
+	case *object.Int:
+		return float64(v.Value)
+	case *object.BigInt, *object.Ratio:
+		f, _ := toRat(v).Float64()
+		return f
+	case *object.Float:
+		return v.Value
+	default:
+		return 0
 	}
-	return &object.Int{Value: ret}, nil
 }
 
-func div(args ...object.Object) (object.Object, error) {
+func mul(args ...object.Object) (object.Object, error) {
 	if len(args) < 2 {
-		return nil, makeArgsLenErr("__div__", 2, len(args))
-	}
-	firstType := args[0].Type()
-	switch firstType {
-	// switch arg types...
-	case object.TInt:
-		intArgs := make([]int64, len(args))
-		for i, ar := range args {
-			oInt, ok := ar.(*object.Int)
-			if !ok {
-				return nil, makeUnexpectedTypeErr("__div__", i,
-					object.TInt, ar.Type())
-			}
-			intArgs[i] = oInt.Value
-		}
-		return iDiv(intArgs...)
-	case object.TFloat:
-		floatArgs := make([]float64, len(args))
-		for i, ar := range args {
-			oFloat, ok := ar.(*object.Float)
-			if !ok {
-				return nil, makeUnexpectedTypeErr("__div__", i,
-					object.TFloat, ar.Type())
-			}
-			floatArgs[i] = oFloat.Value
+		return nil, makeArgsLenErr("__mul__", 2, len(args))
+	}
+	if strToRep, ok := args[0].(*object.String); ok {
+		intArgs, err := extractIntArgs("__mul__", 1, args)
+		if err != nil {
+			return nil, err
 		}
-		return fDiv(floatArgs...)
-	default:
-		return nil, makeFunNotDefErr("__div__", firstType)
+		return sMul(strToRep, intArgs...)
 	}
+	return arith(mulOp, args)
 }
 
-func fDiv(args ...float64) (object.Object, error) {
-	ret := args[0]
-	for _, v := range args[1:] {
-		ret /= v
+// sMul repeats a string as many times as every count says
+func sMul(strToRep *object.String, muls ...int64) (object.Object, error) {
+	value := strToRep.Value
+	for _, m := range muls {
+		if m < 0 {
+			return nil, diag.Errorf(diag.Runtime, "__mul__: negative repeat count %d", m)
+		}
+		if m > 0 && int64(len(value)) > math.MaxInt32/m {
+			return nil, diag.Errorf(diag.Runtime, "__mul__: repeated string is too long")
+		}
+		value = strings.Repeat(value, int(m))
 	}
-	return &object.Float{Value: ret}, nil
+	return &object.String{Value: value}, nil
 }
 
-func iDiv(args ...int64) (object.Object, error) {
-	ret := args[0]
-	for _, v := range args[1:] {
-		ret /= v
-	}
-	return &object.Int{Value: ret}, nil
+func div(args ...object.Object) (object.Object, error) {
+	return arith(divOp, args)
 }
 
 func sub(args ...object.Object) (object.Object, error) {
-	if len(args) < 2 {
-		return nil, makeArgsLenErr("__sub__", 2, len(args))
-	}
-	firstType := args[0].Type()
-	switch firstType {
-	// switch arg types...
-	case object.TInt:
-		intArgs := make([]int64, len(args))
-		for i, ar := range args {
-			oInt, ok := ar.(*object.Int)
-			if !ok {
-				return nil, makeUnexpectedTypeErr("__sub__", i,
-					object.TInt, ar.Type())
-			}
-			intArgs[i] = oInt.Value
-		}
-		return iSub(intArgs...)
-	case object.TFloat:
-		floatArgs := make([]float64, len(args))
-		for i, ar := range args {
-			oFloat, ok := ar.(*object.Float)
-			if !ok {
-				return nil, makeUnexpectedTypeErr("__sub__", i,
-					object.TFloat, ar.Type())
-			}
-			floatArgs[i] = oFloat.Value
-		}
-		return fSub(floatArgs...)
-	default:
-		return nil, makeFunNotDefErr("__sub__", firstType)
-	}
+	return arith(subOp, args)
 }
 
-func fSub(args ...float64) (object.Object, error) {
-	ret := args[0]
-	for _, v := range args[1:] {
-		ret -= v
-	}
-	return &object.Float{Value: ret}, nil
+func add(args ...object.Object) (object.Object, error) {
+	return arith(addOp, args)
 }
 
-func iSub(args ...int64) (object.Object, error) {
-	ret := args[0]
-	for _, v := range args[1:] {
-		ret -= v
+// glispNumerator returns the numerator of an exact number in lowest terms
+func glispNumerator(args ...object.Object) (object.Object, error) {
+	r, err := extractExactArg("numerator", args)
+	if err != nil {
+		return nil, err
 	}
-	return &object.Int{Value: ret}, nil
+	return object.NewInteger(new(big.Int).Set(r.Num())), nil
 }
 
-func add(args ...object.Object) (object.Object, error) {
-	if len(args) < 2 {
-		return nil, makeArgsLenErr("__add__", 2, len(args))
-	}
-	firstType := args[0].Type()
-	switch firstType {
-	// switch arg types...
-	case object.TInt:
-		intArgs := make([]int64, len(args))
-		for i, ar := range args {
-			oInt, ok := ar.(*object.Int)
-			if !ok {
-				return nil, makeUnexpectedTypeErr("__add__", i,
-					object.TInt, ar.Type())
-			}
-			intArgs[i] = oInt.Value
-		}
-		return iAdd(intArgs...)
-	case object.TFloat:
-		floatArgs := make([]float64, len(args))
-		for i, ar := range args {
-			oFloat, ok := ar.(*object.Float)
-			if !ok {
-				return nil, makeUnexpectedTypeErr("__add__", i,
-					object.TFloat, ar.Type())
-			}
-			floatArgs[i] = oFloat.Value
-		}
-		return fAdd(floatArgs...)
-	default:
-		return nil, makeFunNotDefErr("__add__", firstType)
+// glispDenominator returns the denominator of an exact number
+// in lowest terms, it's 1 for integers
+func glispDenominator(args ...object.Object) (object.Object, error) {
+	r, err := extractExactArg("denominator", args)
+	if err != nil {
+		return nil, err
 	}
+	return object.NewInteger(new(big.Int).Set(r.Denom())), nil
 }
 
-func fAdd(args ...float64) (object.Object, error) {
-	ret := args[0]
-	for _, v := range args[1:] {
-		ret += v
+// exactToInexact converts a number to the nearest float
+func exactToInexact(args ...object.Object) (object.Object, error) {
+	if len(args) != 1 {
+		return nil, diag.Errorf(diag.Arity, "exact->inexact expects exactly 1 arg, %d given", len(args))
 	}
-	return &object.Float{Value: ret}, nil
+	if !isNumber(args[0]) {
+		return nil, makeFunNotDefErr("exact->inexact", args[0].Type())
+	}
+	return &object.Float{Value: toFloat(args[0])}, nil
 }
 
-func iAdd(args ...int64) (object.Object, error) {
-	ret := args[0]
-	for _, v := range args[1:] {
-		ret += v
+func extractExactArg(funName string, args []object.Object) (*big.Rat, error) {
+	if len(args) != 1 {
+		return nil, diag.Errorf(diag.Arity, "%s expects exactly 1 arg, %d given", funName, len(args))
+	}
+	if !isExact(args[0]) {
+		return nil, makeFunNotDefErr(funName, args[0].Type())
 	}
-	return &object.Int{Value: ret}, nil
+	return toRat(args[0]), nil
 }
@@ -3,7 +3,9 @@ package interpreter
 import (
 	"fmt"
 	"strings"
+	"unicode"
 
+	"github.com/pmukhin/glisp/pkg/diag"
 	"github.com/pmukhin/glisp/pkg/object"
 )
 
@@ -15,48 +17,68 @@ var internalFunctionTable = map[string]internalFunc{
 	"/":      div,
 	"*":      mul,
 	"append": glispAppend,
+	"map":    glispMap,
 	"print":  glispPrint,
+	"not":    glispNot,
+	"=":      makeComparison("=", func(c int) bool { return c == 0 }),
+	"<":      makeComparison("<", func(c int) bool { return c < 0 }),
+	">":      makeComparison(">", func(c int) bool { return c > 0 }),
+	"<=":     makeComparison("<=", func(c int) bool { return c <= 0 }),
+	">=":     makeComparison(">=", func(c int) bool { return c >= 0 }),
+	"/=":     notEqual,
+	"equal?": glispEqual,
+	"eq?":    glispEq,
+
+	"numerator":      glispNumerator,
+	"denominator":    glispDenominator,
+	"exact->inexact": exactToInexact,
+
+	"char->int":        charToInt,
+	"int->char":        intToChar,
+	"upcase":           upcase,
+	"downcase":         downcase,
+	"char-alphabetic?": makeRunePredicate("char-alphabetic?", unicode.IsLetter),
+	"char-numeric?":    makeRunePredicate("char-numeric?", unicode.IsDigit),
+	"char-whitespace?": makeRunePredicate("char-whitespace?", unicode.IsSpace),
+	"char-upper-case?": makeRunePredicate("char-upper-case?", unicode.IsUpper),
+	"char-lower-case?": makeRunePredicate("char-lower-case?", unicode.IsLower),
+
+	"get":       glispGet,
+	"assoc":     glispAssoc,
+	"dissoc":    glispDissoc,
+	"keys":      glispKeys,
+	"vals":      glispVals,
+	"contains?": glispContains,
+	"merge":     glispMerge,
 }
 
 func makeArgsLenErr(funName string, expected int, given int) error {
-	return fmt.Errorf("%s expects at least %d args, %d given", funName, expected, given)
+	return diag.Errorf(diag.Arity, "%s expects at least %d args, %d given", funName, expected, given)
 }
 
 func makeFunNotDefErr(funName string, oType object.Type) error {
-	return fmt.Errorf("%s is not defined for type %d", funName, oType)
+	return diag.Errorf(diag.Type, "%s is not defined for type %s", funName, oType)
 }
 
 func makeUnexpectedTypeErr(funName string, pos int, oTypeExp, oTypeGiven object.Type) error {
-	return fmt.Errorf("%s expects positional argument #%d to be of type %s, %s given",
+	return diag.Errorf(diag.Type, "%s expects positional argument #%d to be of type %s, %s given",
 		funName, pos, oTypeExp, oTypeGiven)
 }
 
-func extractIntArgs(args []object.Object) ([]int64, error) {
-	intArgs := make([]int64, len(args))
-	for i, ar := range args {
-		oInt, ok := ar.(*object.Int)
+// extractIntArgs extracts ints of positional args starting from a given one
+func extractIntArgs(funName string, from int, args []object.Object) ([]int64, error) {
+	intArgs := make([]int64, 0, len(args)-from)
+	for i := from; i < len(args); i++ {
+		oInt, ok := args[i].(*object.Int)
 		if !ok {
-			return nil, makeUnexpectedTypeErr("__mul__", i,
-				object.TInt, ar.Type())
+			return nil, makeUnexpectedTypeErr(funName, i,
+				object.TInt, args[i].Type())
 		}
-		intArgs[i] = oInt.Value
+		intArgs = append(intArgs, oInt.Value)
 	}
 	return intArgs, nil
 }
 
-func extractFloatArgs(args []object.Object) ([]float64, error) {
-	floatArgs := make([]float64, len(args))
-	for i, ar := range args {
-		oFloat, ok := ar.(*object.Float)
-		if !ok {
-			return nil, makeUnexpectedTypeErr("__mul__", i,
-				object.TFloat, ar.Type())
-		}
-		floatArgs[i] = oFloat.Value
-	}
-	return floatArgs, nil
-}
-
 func glispPrint(args ...object.Object) (object.Object, error) {
 	strList := make([]string, len(args))
 	for i, v := range args {
@@ -65,5 +87,5 @@ func glispPrint(args ...object.Object) (object.Object, error) {
 
 	fmt.Printf("%s\n", strings.Join(strList, " "))
 
-	return nil, nil
+	return object.Nil, nil
 }
@@ -0,0 +1,151 @@
+package interpreter
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pmukhin/glisp/pkg/object"
+)
+
+// compareObjects returns -1, 0 or 1 if a is less, equal or greater than b,
+// both values must be of the same type unless both are numbers. It's false
+// if the values are unordered, that is one of them is NaN
+func compareObjects(funName string, pos int, a, b object.Object) (int, bool, error) {
+	if isNumber(a) && isNumber(b) {
+		c, ordered := compareNumbers(a, b)
+		return c, ordered, nil
+	}
+	if a.Type() != b.Type() {
+		return 0, false, makeUnexpectedTypeErr(funName, pos, a.Type(), b.Type())
+	}
+
+	switch av := a.(type) {
+	case *object.String:
+		return strings.Compare(av.Value, b.(*object.String).Value), true, nil
+	case *object.Rune:
+		return compareInts(int64(av.Value), int64(b.(*object.Rune).Value)), true, nil
+	case *object.Keyword:
+		// keywords are interned, equal ones are the same pointer
+		if av == b {
+			return 0, true, nil
+		}
+		return strings.Compare(av.Name, b.(*object.Keyword).Name), true, nil
+	default:
+		return 0, false, makeFunNotDefErr(funName, a.Type())
+	}
+}
+
+// compareNumbers compares exact numbers exactly and promotes
+// both numbers to floats otherwise, NaN is unordered to any number
+func compareNumbers(a, b object.Object) (int, bool) {
+	ai, aIsInt := a.(*object.Int)
+	bi, bIsInt := b.(*object.Int)
+	if aIsInt && bIsInt {
+		return compareInts(ai.Value, bi.Value), true
+	}
+	if isExact(a) && isExact(b) {
+		return toRat(a).Cmp(toRat(b)), true
+	}
+
+	af, bf := toFloat(a), toFloat(b)
+	switch {
+	case math.IsNaN(af) || math.IsNaN(bf):
+		return 0, false
+	case af < bf:
+		return -1, true
+	case af > bf:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// makeComparison makes a variadic builtin checking that holds
+// is true for every pair of adjacent args, unordered pairs fail it
+func makeComparison(funName string, holds func(c int) bool) internalFunc {
+	return func(args ...object.Object) (object.Object, error) {
+		if len(args) < 2 {
+			return nil, makeArgsLenErr(funName, 2, len(args))
+		}
+
+		result := true
+		for i := 1; i < len(args); i++ {
+			c, ordered, err := compareObjects(funName, i, args[i-1], args[i])
+			if err != nil {
+				return nil, err
+			}
+			result = result && ordered && holds(c)
+		}
+
+		return &object.Bool{Value: result}, nil
+	}
+}
+
+// notEqual is true if no two args are equal, NaN is equal to nothing
+func notEqual(args ...object.Object) (object.Object, error) {
+	if len(args) < 2 {
+		return nil, makeArgsLenErr("/=", 2, len(args))
+	}
+
+	for i := 0; i < len(args); i++ {
+		for j := i + 1; j < len(args); j++ {
+			c, ordered, err := compareObjects("/=", j, args[i], args[j])
+			if err != nil {
+				return nil, err
+			}
+			if ordered && c == 0 {
+				return &object.Bool{Value: false}, nil
+			}
+		}
+	}
+
+	return &object.Bool{Value: true}, nil
+}
+
+// glispEqual tells if all args are structurally equal
+func glispEqual(args ...object.Object) (object.Object, error) {
+	if len(args) < 2 {
+		return nil, makeArgsLenErr("equal?", 2, len(args))
+	}
+	for i := 1; i < len(args); i++ {
+		if !object.Equal(args[i-1], args[i]) {
+			return &object.Bool{Value: false}, nil
+		}
+	}
+	return &object.Bool{Value: true}, nil
+}
+
+// glispEq tells if all args are the same object, atoms
+// having no identity of their own are compared by value
+func glispEq(args ...object.Object) (object.Object, error) {
+	if len(args) < 2 {
+		return nil, makeArgsLenErr("eq?", 2, len(args))
+	}
+	for i := 1; i < len(args); i++ {
+		if !identical(args[i-1], args[i]) {
+			return &object.Bool{Value: false}, nil
+		}
+	}
+	return &object.Bool{Value: true}, nil
+}
+
+func identical(a, b object.Object) bool {
+	switch a.(type) {
+	case *object.Int, *object.BigInt, *object.Ratio, *object.Float, *object.Rune, *object.Bool,
+		*object.Keyword, *object.Symbol, *object.Builtin:
+		return a.Equal(b)
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,140 @@
+package interpreter
+
+import (
+	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/diag"
+	"github.com/pmukhin/glisp/pkg/object"
+)
+
+// isTruthy tells if a value counts as true in a condition:
+// false, nil and an empty list are false, anything else is true
+func isTruthy(obj object.Object) bool {
+	switch v := obj.(type) {
+	case *object.NilType:
+		return false
+	case *object.Bool:
+		return v.Value
+	case *object.List:
+		return len(v.Elements) > 0
+	default:
+		return true
+	}
+}
+
+// evalIf evaluates only the branch chosen by the condition
+func evalIf(node ast.Node, ctx object.Context) (object.Object, error) {
+	ifExpr := node.(*ast.IfExpression)
+	cond, err := Eval(ifExpr.Condition, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTruthy(cond) {
+		return evalTail(ifExpr.Consequence, ctx)
+	}
+	if ifExpr.Alternative != nil {
+		return evalTail(ifExpr.Alternative, ctx)
+	}
+	return object.Nil, nil
+}
+
+// evalCond evaluates the body of the first clause whose condition holds
+func evalCond(node ast.Node, ctx object.Context) (object.Object, error) {
+	condExpr := node.(*ast.CondExpression)
+	for _, clause := range condExpr.Clauses {
+		if clause.Condition == nil {
+			return evalBody(clause.Body, ctx)
+		}
+
+		cond, err := Eval(clause.Condition, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(cond) {
+			continue
+		}
+		// a clause without body yields the value of its condition
+		if len(clause.Body) == 0 {
+			return cond, nil
+		}
+		return evalBody(clause.Body, ctx)
+	}
+
+	return object.Nil, nil
+}
+
+// evalWhen ...
+func evalWhen(node ast.Node, ctx object.Context) (object.Object, error) {
+	whenExpr := node.(*ast.WhenExpression)
+	cond, err := Eval(whenExpr.Condition, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isTruthy(cond) {
+		return object.Nil, nil
+	}
+	return evalBody(whenExpr.Body, ctx)
+}
+
+// evalUnless ...
+func evalUnless(node ast.Node, ctx object.Context) (object.Object, error) {
+	unlessExpr := node.(*ast.UnlessExpression)
+	cond, err := Eval(unlessExpr.Condition, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTruthy(cond) {
+		return object.Nil, nil
+	}
+	return evalBody(unlessExpr.Body, ctx)
+}
+
+// evalAnd returns the first false value or the last one
+// if all of them are true, the rest args are not evaluated
+func evalAnd(node ast.Node, ctx object.Context) (object.Object, error) {
+	andExpr := node.(*ast.AndExpression)
+
+	var lastVal object.Object = &object.Bool{Value: true}
+	for _, arg := range andExpr.Args {
+		val, err := Eval(arg, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(val) {
+			return val, nil
+		}
+		lastVal = val
+	}
+
+	return lastVal, nil
+}
+
+// evalOr returns the first true value or the last one
+// if none of them is true, the rest args are not evaluated
+func evalOr(node ast.Node, ctx object.Context) (object.Object, error) {
+	orExpr := node.(*ast.OrExpression)
+
+	var lastVal object.Object = &object.Bool{Value: false}
+	for _, arg := range orExpr.Args {
+		val, err := Eval(arg, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if isTruthy(val) {
+			return val, nil
+		}
+		lastVal = val
+	}
+
+	return lastVal, nil
+}
+
+// glispNot ...
+func glispNot(args ...object.Object) (object.Object, error) {
+	if len(args) != 1 {
+		return nil, diag.Errorf(diag.Arity, "not expects exactly 1 arg, %d given", len(args))
+	}
+	return &object.Bool{Value: !isTruthy(args[0])}, nil
+}
@@ -2,8 +2,10 @@ package interpreter
 
 import (
 	"fmt"
+	"math/big"
 
 	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/diag"
 	"github.com/pmukhin/glisp/pkg/object"
 )
 
@@ -18,20 +20,49 @@ func init() {
 	typeToEvaluatorFunc[ast.ProgramExpr] = evalProgram
 	typeToEvaluatorFunc[ast.FunCall] = evalFunctionCall
 	typeToEvaluatorFunc[ast.IntExpr] = evalInt
+	typeToEvaluatorFunc[ast.BigIntExpr] = evalBigInt
 	typeToEvaluatorFunc[ast.FloatExpr] = evalFloat
 	typeToEvaluatorFunc[ast.StringExpr] = evalString
 	typeToEvaluatorFunc[ast.RuneExpr] = evalRune
+	typeToEvaluatorFunc[ast.KeywordExpr] = evalKeyword
+	typeToEvaluatorFunc[ast.NilExpr] = evalNil
+	typeToEvaluatorFunc[ast.BoolExpr] = evalBool
 	typeToEvaluatorFunc[ast.ListExpr] = evalList
 	typeToEvaluatorFunc[ast.VectorExpr] = evalVector
+	typeToEvaluatorFunc[ast.MapExpr] = evalMap
 	typeToEvaluatorFunc[ast.DefVarExpr] = evalDefVar
+	typeToEvaluatorFunc[ast.DefunExpr] = evalDefun
+	typeToEvaluatorFunc[ast.LambdaExpr] = evalLambda
+	typeToEvaluatorFunc[ast.IfExpr] = evalIf
+	typeToEvaluatorFunc[ast.CondExpr] = evalCond
+	typeToEvaluatorFunc[ast.WhenExpr] = evalWhen
+	typeToEvaluatorFunc[ast.UnlessExpr] = evalUnless
+	typeToEvaluatorFunc[ast.AndExpr] = evalAnd
+	typeToEvaluatorFunc[ast.OrExpr] = evalOr
+	typeToEvaluatorFunc[ast.LetExpr] = evalLet
+	typeToEvaluatorFunc[ast.SetqExpr] = evalSetq
+	typeToEvaluatorFunc[ast.WhileExpr] = evalWhile
+	typeToEvaluatorFunc[ast.DotimesExpr] = evalDotimes
+	typeToEvaluatorFunc[ast.DolistExpr] = evalDolist
+	typeToEvaluatorFunc[ast.DefmacroExpr] = evalDefmacro
+	typeToEvaluatorFunc[ast.MacroCallExpr] = evalMacroCall
+	typeToEvaluatorFunc[ast.QuoteExpr] = evalQuote
+	typeToEvaluatorFunc[ast.QuasiQuoteExpr] = evalQuasiQuote
 	typeToEvaluatorFunc[ast.Expr] = evalExpr
 	typeToEvaluatorFunc[ast.IdentExpr] = evalName
 }
 
-// evalName ...
+// evalName looks a name up in the context falling back to internal functions
 func evalName(node ast.Node, ctx object.Context) (object.Object, error) {
 	id := node.(*ast.IdentifierExpression)
-	return ctx.Get(id.Value)
+	val, err := ctx.Get(id.Value)
+	if err == nil {
+		return val, nil
+	}
+	if fun, ok := internalFunctionTable[id.Value]; ok {
+		return &object.Builtin{Name: id.Value, Fn: object.BuiltinFunction(fun)}, nil
+	}
+	return nil, err
 }
 
 // evalDefVar defines a variable in a given context
@@ -41,12 +72,130 @@ func evalDefVar(node ast.Node, ctx object.Context) (object.Object, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = ctx.Set(defVarExpr.Name.Value, value)
-	if err != nil {
-		return nil, nil
+	if err := ctx.Set(defVarExpr.Name.Value, value); err != nil {
+		return nil, err
+	}
+
+	return &object.Symbol{Name: defVarExpr.Name.Value}, nil
+}
+
+// evalSetq assigns values to already defined variables
+// returning the last assigned value
+func evalSetq(node ast.Node, ctx object.Context) (object.Object, error) {
+	setqExpr := node.(*ast.SetqExpression)
+
+	var lastVal object.Object = object.Nil
+	for i, name := range setqExpr.Names {
+		value, err := Eval(setqExpr.Values[i], ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Assign(name.Value, value); err != nil {
+			return nil, err
+		}
+		lastVal = value
+	}
+
+	return lastVal, nil
+}
+
+// evalDefun defines a function in a given context,
+// the function closes over this context
+func evalDefun(node ast.Node, ctx object.Context) (object.Object, error) {
+	defunExpr := node.(*ast.DefunExpression)
+	fn := &object.Function{
+		Name:   defunExpr.Name.Value,
+		Params: paramNames(defunExpr.Params),
+		Rest:   restName(defunExpr.Rest),
+		Body:   defunExpr.Body,
+		Env:    ctx,
+	}
+	if err := ctx.Set(fn.Name, fn); err != nil {
+		return nil, err
+	}
+
+	return &object.Symbol{Name: fn.Name}, nil
+}
+
+// evalLambda creates an anonymous function closing over a given context
+func evalLambda(node ast.Node, ctx object.Context) (object.Object, error) {
+	lambdaExpr := node.(*ast.LambdaExpression)
+	return &object.Function{
+		Name:   "lambda",
+		Params: paramNames(lambdaExpr.Params),
+		Rest:   restName(lambdaExpr.Rest),
+		Body:   lambdaExpr.Body,
+		Env:    ctx,
+	}, nil
+}
+
+func paramNames(params []*ast.IdentifierExpression) []string {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Value
+	}
+	return names
+}
+
+func restName(rest *ast.IdentifierExpression) string {
+	if rest == nil {
+		return ""
+	}
+	return rest.Value
+}
+
+// bindArgs creates a child of env holding args bound to params,
+// args left after params are bound to rest as a list
+func bindArgs(name string, params []string, rest string,
+	args []object.Object, env object.Context) (object.Context, error) {
+	if len(args) < len(params) || (rest == "" && len(args) > len(params)) {
+		if rest != "" {
+			return nil, makeArgsLenErr(name, len(params), len(args))
+		}
+		return nil, diag.Errorf(diag.Arity, "%s expects %d args, %d given",
+			name, len(params), len(args))
+	}
+
+	child := env.NewChild()
+	for i, param := range params {
+		if err := child.Set(param, args[i]); err != nil {
+			return nil, err
+		}
+	}
+	if rest != "" {
+		restArgs := make([]object.Object, len(args)-len(params))
+		copy(restArgs, args[len(params):])
+		if err := child.Set(rest, &object.List{Elements: restArgs}); err != nil {
+			return nil, err
+		}
+	}
+
+	return child, nil
+}
+
+// evalLet evaluates body in a child scope holding the bindings,
+// let* evaluates every binding in a scope seeing the previous ones
+func evalLet(node ast.Node, ctx object.Context) (object.Object, error) {
+	letExpr := node.(*ast.LetExpression)
+
+	env := ctx.NewChild()
+	for _, binding := range letExpr.Bindings {
+		evalCtx := ctx
+		if letExpr.Sequential {
+			// a fresh scope per binding allows rebinding the same name
+			env = env.NewChild()
+			evalCtx = env
+		}
+		value, err := Eval(binding.Value, evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		if err := env.Set(binding.Name.Value, value); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil, nil
+	return evalBody(letExpr.Body, env)
 }
 
 // evalString ...
@@ -56,17 +205,9 @@ func evalString(node ast.Node, ctx object.Context) (object.Object, error) {
 }
 
 // evalList ...
+// lists are parsed only as data, so a list stands for itself
 func evalList(node ast.Node, ctx object.Context) (object.Object, error) {
-	listStmt := node.(*ast.ListExpression)
-	list := &object.List{Elements: make([]object.Object, 0, 32)}
-	for _, astElem := range listStmt.Elements {
-		oElem, err := Eval(astElem, ctx)
-		if err != nil {
-			return nil, err
-		}
-		list.Elements = append(list.Elements, oElem)
-	}
-	return list, nil
+	return quoteDatum(node, ctx)
 }
 
 // evalVector ...
@@ -74,26 +215,58 @@ func evalVector(node ast.Node, ctx object.Context) (object.Object, error) {
 	listStmt := node.(*ast.VectorExpression)
 	list := &object.Vector{Elements: make([]object.Object, 0, 32)}
 
-	var fType object.Type = -1
 	for _, astElem := range listStmt.Elements {
 		oElem, err := Eval(astElem, ctx)
 		if err != nil {
 			return nil, err
 		}
-		if fType == -1 {
-			fType = oElem.Type()
-		} else {
-			if fType != oElem.Type() {
-				return nil, fmt.Errorf("vectors contain only values "+
-					"of the same type: %s is expected, %s given", fType, oElem.Type())
-			}
-		}
-
 		list.Elements = append(list.Elements, oElem)
 	}
+	if err := checkVectorElements(list.Elements); err != nil {
+		return nil, err
+	}
 	return list, nil
 }
 
+// checkVectorElements checks that all elements of a vector are of the same type
+func checkVectorElements(elements []object.Object) error {
+	if len(elements) == 0 {
+		return nil
+	}
+	fType := vectorElemType(elements[0])
+	for _, el := range elements[1:] {
+		if vectorElemType(el) != fType {
+			return diag.Errorf(diag.Type, "vectors contain only values "+
+				"of the same type: %s is expected, %s given", fType, el.Type())
+		}
+	}
+	return nil
+}
+
+// vectorElemType is the type of an element as vectors see it,
+// all exact numbers are integers of various size to them
+func vectorElemType(o object.Object) object.Type {
+	if isExact(o) {
+		return object.TInt
+	}
+	return o.Type()
+}
+
+// evalNil ...
+func evalNil(node ast.Node, ctx object.Context) (object.Object, error) {
+	return object.Nil, nil
+}
+
+// evalBool ...
+func evalBool(node ast.Node, ctx object.Context) (object.Object, error) {
+	return &object.Bool{Value: node.(*ast.BoolExpression).Value}, nil
+}
+
+// evalKeyword ...
+func evalKeyword(node ast.Node, ctx object.Context) (object.Object, error) {
+	return object.NewKeyword(node.(*ast.KeywordExpression).Value), nil
+}
+
 // evalRune ...
 func evalRune(node ast.Node, ctx object.Context) (object.Object, error) {
 	astRuneStmt := node.(*ast.RuneExpression)
@@ -112,26 +285,42 @@ func evalInt(node ast.Node, ctx object.Context) (object.Object, error) {
 	return &object.Int{Value: astInt.Value}, nil
 }
 
+// evalBigInt ...
+func evalBigInt(node ast.Node, ctx object.Context) (object.Object, error) {
+	astBigInt := node.(*ast.BigIntegerExpression)
+	return &object.BigInt{Value: new(big.Int).Set(astBigInt.Value)}, nil
+}
+
 // evalFloat ...
 func evalFloat(node ast.Node, ctx object.Context) (object.Object, error) {
 	astFloat := node.(*ast.FloatExpression)
 	return &object.Float{Value: astFloat.Value}, nil
 }
 
-// Eval ...
+// Eval evaluates a node running any tail call it results in
 func Eval(n ast.Node, ctx object.Context) (object.Object, error) {
+	return resolve(evalTail(n, ctx))
+}
+
+// evalTail evaluates a node standing in tail position,
+// the result may be a *tailCall to be run by the caller
+func evalTail(n ast.Node, ctx object.Context) (object.Object, error) {
 	evaluator, ok := typeToEvaluatorFunc[n.Type()]
 	if !ok {
-		return nil, fmt.Errorf("can not evaluate %s", n.Type())
+		return nil, diag.Locate(diag.Errorf(diag.Runtime, "can not evaluate %s", n.Type()), n)
+	}
+	res, err := evaluator(n, ctx)
+	if err != nil {
+		return nil, diag.Locate(err, n)
 	}
-	return evaluator(n, ctx)
+	return res, nil
 }
 
 // evalProgram ...
 func evalProgram(node ast.Node, ctx object.Context) (object.Object, error) {
 	program := node.(*ast.Program)
 
-	var lastVal object.Object = nil
+	var lastVal object.Object = object.Nil
 	for _, statement := range program.Statements {
 		val, err := Eval(statement, ctx)
 		if err != nil {
@@ -145,11 +334,13 @@ func evalProgram(node ast.Node, ctx object.Context) (object.Object, error) {
 
 func evalFunctionCall(node ast.Node, ctx object.Context) (object.Object, error) {
 	fc := node.(*ast.FunctionCall)
-	fName := fc.Callee.(*ast.IdentifierExpression).Value
-	fun, ok := internalFunctionTable[fName]
 
-	if !ok {
-		return nil, fmt.Errorf("function `%s` is not defined", fName)
+	callee, err := Eval(fc.Callee, ctx)
+	if err != nil {
+		if id, ok := fc.Callee.(*ast.IdentifierExpression); ok {
+			return nil, diag.Errorf(diag.Unbound, "function `%s` is not defined", id.Value)
+		}
+		return nil, err
 	}
 
 	args := make([]object.Object, len(fc.Args))
@@ -161,5 +352,105 @@ func evalFunctionCall(node ast.Node, ctx object.Context) (object.Object, error)
 		args[i] = objArg
 	}
 
-	return fun(args...)
+	// a user function call is postponed until the caller's
+	// trampoline runs it so that tail recursion does not grow the stack
+	if fn, ok := callee.(*object.Function); ok {
+		return &tailCall{fn: fn, args: args, node: fc}, nil
+	}
+
+	return apply(callee, args)
+}
+
+// apply calls a function value with already evaluated args
+func apply(callee object.Object, args []object.Object) (object.Object, error) {
+	switch fn := callee.(type) {
+	case *object.Function:
+		return applyFunction(fn, args)
+	case *object.Builtin:
+		return fn.Fn(args...)
+	default:
+		return nil, diag.Errorf(diag.Type, "%s is not a function", callee.Type())
+	}
+}
+
+// applyFunction binds args to params in a context enclosed
+// by the one the function has been defined in and evaluates its body,
+// calls in tail position are run in a loop here instead of recursively
+func applyFunction(fn *object.Function, args []object.Object) (object.Object, error) {
+	return runTailCalls(&tailCall{fn: fn, args: args})
+}
+
+// runTailCalls runs a call and every tail call it results in
+func runTailCalls(tc *tailCall) (object.Object, error) {
+	for {
+		env, err := bindArgs(tc.fn.Name, tc.fn.Params, tc.fn.Rest, tc.args, tc.fn.Env)
+		if err != nil {
+			return nil, diag.Locate(err, tc.node)
+		}
+
+		res, err := evalBody(tc.fn.Body, env)
+		if err != nil {
+			return nil, err
+		}
+		next, ok := res.(*tailCall)
+		if !ok {
+			return res, nil
+		}
+		tc = next
+	}
+}
+
+// evalBody evaluates expressions one by one returning the last value,
+// the last expression is in tail position
+func evalBody(body []ast.Expression, ctx object.Context) (object.Object, error) {
+	for i, expr := range body {
+		if i == len(body)-1 {
+			return evalTail(expr, ctx)
+		}
+		if _, err := Eval(expr, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return object.Nil, nil
+}
+
+// tailCall is a postponed call of a user function, it never
+// leaves the interpreter as Eval always runs it
+type tailCall struct {
+	fn   *object.Function
+	args []object.Object
+	// node is the call, it's nil for calls made by builtins
+	node ast.Node
+}
+
+// String ...
+func (tc tailCall) String() string {
+	return fmt.Sprintf("<tail call %s>", tc.fn.Name)
+}
+
+// Type ...
+func (tailCall) Type() object.Type {
+	return object.TFunction
+}
+
+// Equal ...
+func (tc *tailCall) Equal(other object.Object) bool {
+	return other == object.Object(tc)
+}
+
+// Hash ...
+func (tc *tailCall) Hash() uint64 {
+	return tc.fn.Hash()
+}
+
+// resolve runs a tail call if evaluation resulted in one
+func resolve(res object.Object, err error) (object.Object, error) {
+	if err != nil {
+		return nil, err
+	}
+	if tc, ok := res.(*tailCall); ok {
+		return runTailCalls(tc)
+	}
+	return res, nil
 }
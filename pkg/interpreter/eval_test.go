@@ -1,25 +1,48 @@
 package interpreter
 
 import (
+	"reflect"
+	"runtime/debug"
 	"testing"
+
 	"github.com/pmukhin/glisp/pkg/ast"
-	"github.com/pmukhin/glisp/pkg/token"
+	"github.com/pmukhin/glisp/pkg/diag"
 	"github.com/pmukhin/glisp/pkg/object"
-	"reflect"
+	"github.com/pmukhin/glisp/pkg/parser"
+	"github.com/pmukhin/glisp/pkg/scanner"
+	"github.com/pmukhin/glisp/pkg/token"
 )
 
+// pos makes a position of a given offset in a single line source
+func pos(offset int) token.Position {
+	return token.Position{Offset: offset, Line: 1, Column: offset + 1}
+}
+
+// run parses and evaluates source in a fresh context
+func run(t *testing.T, source string) object.Object {
+	program, err := parser.New(scanner.New(source)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Eval(program, object.NewContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
 func TestEval_GetVar(t *testing.T) {
 	program := &ast.Program{
 		Statements: []ast.Statement{
 			&ast.ExpressionStatement{
 				Expression: &ast.IdentifierExpression{
-					Token: token.New(token.Identifier, 0, "int-var"),
+					Token: token.New(token.Identifier, pos(0), "int-var"),
 					Value: "int-var",
 				},
 			},
 		},
 	}
-	expVal := &object.Int{5}
+	expVal := &object.Int{Value: 5}
 	ctx := object.NewContext()
 	ctx.Set("int-var", expVal)
 
@@ -39,26 +62,29 @@ func TestEval_DefVar(t *testing.T) {
 		Statements: []ast.Statement{
 			&ast.ExpressionStatement{
 				Expression: &ast.DefVarExpression{
-					Token: token.New(token.Identifier, 1, "defvar"),
+					Token: token.New(token.Identifier, pos(1), "defvar"),
 					Name: &ast.IdentifierExpression{
-						Token: token.New(token.Identifier, 8, "int-list"),
+						Token: token.New(token.Identifier, pos(8), "int-list"),
 						Value: "int-list",
 					},
-					Value: &ast.ListExpression{
-						Token: token.New(token.SingleQuote, 17),
-						Elements: []ast.Expression{
-							&ast.IntegerExpression{
-								Token: token.New(token.Integer, 19, "1"),
-								Value: 1,
-							},
-							&ast.IntegerExpression{
-								Token: token.New(token.Integer, 21, "2"),
-								Value: 2,
+					Value: &ast.QuoteExpression{
+						Token: token.New(token.SingleQuote, pos(17)),
+						Value: &ast.ListExpression{
+							Token: token.New(token.ParenOp, pos(18)),
+							Elements: []ast.Expression{
+								&ast.IntegerExpression{
+									Token: token.New(token.Integer, pos(19), "1"),
+									Value: 1,
+								},
+								&ast.IntegerExpression{
+									Token: token.New(token.Integer, pos(21), "2"),
+									Value: 2,
+								},
 							},
 						},
 					},
 					Comment: &ast.StringExpression{
-						Token: token.New(token.String, 24, "a list of ints"),
+						Token: token.New(token.String, pos(24), "a list of ints"),
 						Value: "a list of ints",
 					},
 				},
@@ -72,8 +98,8 @@ func TestEval_DefVar(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	if res != nil {
-		t.Error("defvar should return nil")
+	if !reflect.DeepEqual(res, &object.Symbol{Name: "int-list"}) {
+		t.Errorf("defvar should return the name defined, got %s", res)
 	}
 	if ob, err := ctx.Get("int-list"); err != nil {
 		t.Error(err)
@@ -95,18 +121,18 @@ func TestEval_Vector(t *testing.T) {
 		Statements: []ast.Statement{
 			&ast.ExpressionStatement{
 				Expression: &ast.VectorExpression{
-					Token: token.New(token.BracketOp, 0),
+					Token: token.New(token.BracketOp, pos(0)),
 					Elements: []ast.Expression{
 						&ast.StringExpression{
-							Token: token.New(token.String, 1, "a"),
+							Token: token.New(token.String, pos(1), "a"),
 							Value: "a",
 						},
 						&ast.StringExpression{
-							Token: token.New(token.String, 5, "b"),
+							Token: token.New(token.String, pos(5), "b"),
 							Value: "b",
 						},
 						&ast.StringExpression{
-							Token: token.New(token.String, 9, "c"),
+							Token: token.New(token.String, pos(9), "c"),
 							Value: "c",
 						},
 					},
@@ -136,27 +162,30 @@ func TestEval_ListAppend(t *testing.T) {
 		Statements: []ast.Statement{
 			&ast.ExpressionStatement{
 				Expression: &ast.FunctionCall{
-					Token: token.New(token.ParenOp, 0),
+					Token: token.New(token.ParenOp, pos(0)),
 					Callee: &ast.IdentifierExpression{
-						Token: token.New(token.Identifier, 1, "append"),
+						Token: token.New(token.Identifier, pos(1), "append"),
 						Value: "append",
 					},
 					Args: []ast.Expression{
-						&ast.ListExpression{
-							Token: token.New(token.SingleQuote, 8),
-							Elements: []ast.Expression{
-								&ast.IntegerExpression{
-									Token: token.New(token.Integer, 10, "1"),
-									Value: 1,
-								},
-								&ast.IntegerExpression{
-									Token: token.New(token.Integer, 12, "2"),
-									Value: 2,
+						&ast.QuoteExpression{
+							Token: token.New(token.SingleQuote, pos(8)),
+							Value: &ast.ListExpression{
+								Token: token.New(token.ParenOp, pos(9)),
+								Elements: []ast.Expression{
+									&ast.IntegerExpression{
+										Token: token.New(token.Integer, pos(10), "1"),
+										Value: 1,
+									},
+									&ast.IntegerExpression{
+										Token: token.New(token.Integer, pos(12), "2"),
+										Value: 2,
+									},
 								},
 							},
 						},
 						&ast.IntegerExpression{
-							Token: token.New(token.Integer, 15, "3"),
+							Token: token.New(token.Integer, pos(15), "3"),
 							Value: 3,
 						},
 					},
@@ -172,7 +201,7 @@ func TestEval_ListAppend(t *testing.T) {
 
 	list := res.(*object.List)
 	expectedElements := []object.Object{
-		&object.Int{1}, &object.Int{2}, &object.Int{3},
+		&object.Int{Value: 1}, &object.Int{Value: 2}, &object.Int{Value: 3},
 	}
 
 	if !reflect.DeepEqual(list.Elements, expectedElements) {
@@ -185,18 +214,18 @@ func TestEval_ArithmeticFunction(t *testing.T) {
 		Statements: []ast.Statement{
 			&ast.ExpressionStatement{
 				Expression: &ast.FunctionCall{
-					Token: token.New(token.ParenOp, 0),
+					Token: token.New(token.ParenOp, pos(0)),
 					Callee: &ast.IdentifierExpression{
-						Token: token.New(token.Identifier, 1, "*"),
+						Token: token.New(token.Identifier, pos(1), "*"),
 						Value: "*",
 					},
 					Args: []ast.Expression{
 						&ast.IntegerExpression{
-							Token: token.New(token.Integer, 3, "2"),
+							Token: token.New(token.Integer, pos(3), "2"),
 							Value: 2,
 						},
 						&ast.IntegerExpression{
-							Token: token.New(token.Integer, 5, "5"),
+							Token: token.New(token.Integer, pos(5), "5"),
 							Value: 5,
 						},
 					},
@@ -215,3 +244,795 @@ func TestEval_ArithmeticFunction(t *testing.T) {
 		t.Errorf("expected 10, got %d", iVal.Value)
 	}
 }
+
+func TestEval_NumericPromotion(t *testing.T) {
+	tests := map[string]object.Object{
+		`(+ 1 2)`:                  &object.Int{Value: 3},
+		`(+ 1 2.5)`:                &object.Float{Value: 3.5},
+		`(+ 2.5 1)`:                &object.Float{Value: 3.5},
+		`(- 10 0.5 2)`:             &object.Float{Value: 7.5},
+		`(* 2 3 0.5)`:              &object.Float{Value: 3},
+		`(/ 8 2)`:                  &object.Int{Value: 4},
+		`(/ 7 2.0)`:                &object.Float{Value: 3.5},
+		`(* "ab" 2)`:               &object.String{Value: "abab"},
+		`(= 1 1.0)`:                &object.Bool{Value: true},
+		`(< 1 1.5 2)`:              &object.Bool{Value: true},
+		`(>= 2.0 2 1)`:             &object.Bool{Value: true},
+		`(/= 1 1.0)`:               &object.Bool{Value: false},
+		`(equal? 1 1.0)`:           &object.Bool{Value: false},
+		`(= (/ 0.0 0) 5)`:          &object.Bool{Value: false},
+		`(= (/ 0.0 0) (/ 0.0 0))`:  &object.Bool{Value: false},
+		`(>= (/ 0.0 0) 1)`:         &object.Bool{Value: false},
+		`(< 1 (/ 0.0 0))`:          &object.Bool{Value: false},
+		`(<= (/ 1 2) (/ 0.0 0))`:   &object.Bool{Value: false},
+		`(/= (/ 0.0 0) 5)`:         &object.Bool{Value: true},
+		`(/= (/ 0.0 0) (/ 0.0 0))`: &object.Bool{Value: true},
+	}
+	for source, expected := range tests {
+		if res := run(t, source); !reflect.DeepEqual(res, expected) {
+			t.Errorf("%s: expected %s, got %s", source, expected, res)
+		}
+	}
+}
+
+func TestEval_ExactArithmetic(t *testing.T) {
+	tests := map[string]string{
+		`(+ 9223372036854775807 1)`:                       "9223372036854775808",
+		`(- -9223372036854775808 1)`:                      "-9223372036854775809",
+		`(* 4294967296 4294967296)`:                       "18446744073709551616",
+		`(* -1 -9223372036854775808)`:                     "9223372036854775808",
+		`(/ -9223372036854775808 -1)`:                     "9223372036854775808",
+		`(- (+ 9223372036854775807 1) 1)`:                 "9223372036854775807",
+		`(+ 18446744073709551616 0.5)`:                    "18446744073709551616.000000",
+		`(/ 1 3)`:                                         "1/3",
+		`(/ 6 4)`:                                         "3/2",
+		`(+ (/ 1 3) (/ 2 3))`:                             "1",
+		`(* (/ 1 4) 0.5)`:                                 "0.125000",
+		`(numerator (/ 6 4))`:                             "3",
+		`(denominator (/ 6 4))`:                           "2",
+		`(denominator 5)`:                                 "1",
+		`(exact->inexact (/ 1 4))`:                        "0.250000",
+		`(< (/ 1 3) 0.34 (/ 1 2) 1 18446744073709551616)`: "true",
+		`(= (/ 2 4) 0.5)`:                                 "true",
+		`(equal? (/ 2 4) (/ 1 2))`:                        "true",
+		`(get {(/ 1 2) :half} (/ 2 4))`:                   ":half",
+		`(defmacro m () (/ 1 3)) (* (m) 3)`:               "1",
+	}
+	for source, expected := range tests {
+		if res := run(t, source); res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, res)
+		}
+	}
+
+	vectors := map[string]string{
+		`[1 9223372036854775808]`: "[1 9223372036854775808]",
+		`[1 (/ 1 2)]`:             "[1 1/2]",
+		`(defvar big (* 4611686018427387904 2)) [big 1]`: "[9223372036854775808 1]",
+	}
+	for source, expected := range vectors {
+		if res := run(t, source); res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, res)
+		}
+	}
+
+	if res := run(t, `(+ 9223372036854775807 1 -1)`); res.Type() != object.TInt {
+		t.Errorf("expected a result fitting in int64 to be TInt, got %s", res.Type())
+	}
+}
+
+func TestEval_Defun(t *testing.T) {
+	res := run(t, `
+(defun sq (x) "squares x" (* x x))
+(sq 7)`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 49 {
+		t.Errorf("expected 49, got %d", iVal.Value)
+	}
+}
+
+func TestEval_DefunClosure(t *testing.T) {
+	res := run(t, `
+(defvar base 10)
+(defun add-base (x) (+ x base))
+(defun twice (x) (add-base (add-base x)))
+(twice 1)`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 21 {
+		t.Errorf("expected 21, got %d", iVal.Value)
+	}
+}
+
+func TestEval_DefunArity(t *testing.T) {
+	program, err := parser.New(scanner.New(`(defun sq (x) (* x x)) (sq 1 2)`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Eval(program, object.NewContext()); err == nil {
+		t.Error("expected an arity error")
+	}
+}
+
+func TestEval_DefunParamShadowsGlobal(t *testing.T) {
+	res := run(t, `
+(defvar x 100)
+(defun inc (x) (+ x 1))
+(+ (inc 1) x)`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 102 {
+		t.Errorf("expected 102, got %d", iVal.Value)
+	}
+}
+
+func TestEval_LambdaCall(t *testing.T) {
+	res := run(t, `((lambda (x) (* x x)) 5)`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 25 {
+		t.Errorf("expected 25, got %d", iVal.Value)
+	}
+}
+
+func TestEval_FunctionAsValue(t *testing.T) {
+	res := run(t, `
+(defun make-adder (n) (lambda (x) (+ x n)))
+(defvar add-two (make-adder 2))
+(defvar plus +)
+(plus (add-two 3) 1)`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 6 {
+		t.Errorf("expected 6, got %d", iVal.Value)
+	}
+}
+
+func TestEval_MapBuiltin(t *testing.T) {
+	res := run(t, `(map + '(1 2 3) '(10 20 30))`)
+
+	expected := &object.List{Elements: []object.Object{
+		&object.Int{Value: 11}, &object.Int{Value: 22}, &object.Int{Value: 33},
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+}
+
+func TestEval_If(t *testing.T) {
+	tests := map[string]int64{
+		`(if '(1) 1 2)`:            1,
+		`(if '() 1 2)`:             2,
+		`(if (print) 1 2)`:         2,
+		`(if [] 1 (undefined-fn))`: 1,
+	}
+	for source, expected := range tests {
+		iVal := run(t, source).(*object.Int)
+		if iVal.Value != expected {
+			t.Errorf("%s: expected %d, got %d", source, expected, iVal.Value)
+		}
+	}
+
+	if res := run(t, `(if '() 1)`); res != object.Nil {
+		t.Errorf("expected if without else branch to return nil, got %s", res)
+	}
+}
+
+func TestEval_Cond(t *testing.T) {
+	res := run(t, `
+(defun classify (x)
+  (cond ('() 1)
+        (x 2 3)
+        (else 4)))
+(+ (classify '(1)) (classify '()))`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 7 {
+		t.Errorf("expected 7, got %d", iVal.Value)
+	}
+}
+
+func TestEval_WhenUnless(t *testing.T) {
+	if res := run(t, `(when '(1) 1 2)`).(*object.Int); res.Value != 2 {
+		t.Errorf("expected 2, got %d", res.Value)
+	}
+	if res := run(t, `(when '() (undefined-fn))`); res != object.Nil {
+		t.Errorf("expected nil, got %s", res)
+	}
+	if res := run(t, `(unless '() 3)`).(*object.Int); res.Value != 3 {
+		t.Errorf("expected 3, got %d", res.Value)
+	}
+	if res := run(t, `(unless 1 (undefined-fn))`); res != object.Nil {
+		t.Errorf("expected nil, got %s", res)
+	}
+}
+
+func TestEval_Comparison(t *testing.T) {
+	tests := map[string]bool{
+		`(= 1 1 1)`:             true,
+		`(= 1 1 2)`:             false,
+		`(< 1 2 3)`:             true,
+		`(< 1 3 2)`:             false,
+		`(> 3.5 2.5)`:           true,
+		`(<= 1 1 2)`:            true,
+		`(>= 2 2 3)`:            false,
+		`(/= 1 2 3)`:            true,
+		`(/= 1 2 1)`:            false,
+		`(< "abc" "abd")`:       true,
+		`(= "abc" "abc")`:       true,
+		`(not (< 2 1))`:         true,
+		`(not '(1))`:            false,
+		`(and (< 1 2) (> 2 1))`: true,
+		`(or (< 2 1) (> 1 2))`:  false,
+	}
+	for source, expected := range tests {
+		bVal := run(t, source).(*object.Bool)
+		if bVal.Value != expected {
+			t.Errorf("%s: expected %t, got %t", source, expected, bVal.Value)
+		}
+	}
+
+	res, err := internalFunctionTable["<"](&object.Rune{Value: 'a'}, &object.Rune{Value: 'b'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.(*object.Bool).Value {
+		t.Error("expected 'a' to be less than 'b'")
+	}
+}
+
+func TestEval_ComparisonTypeMismatch(t *testing.T) {
+	program, err := parser.New(scanner.New(`(< 1 "2")`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Eval(program, object.NewContext()); err == nil {
+		t.Error("expected a type error")
+	}
+}
+
+func TestEval_AndOrShortCircuit(t *testing.T) {
+	if res := run(t, `(and 1 '() (undefined-fn))`).(*object.List); len(res.Elements) != 0 {
+		t.Errorf("expected and to stop at the empty list, got %s", res)
+	}
+	if res := run(t, `(or '() 2 (undefined-fn))`).(*object.Int); res.Value != 2 {
+		t.Errorf("expected or to return 2, got %s", res)
+	}
+	if res := run(t, `(and 1 2)`).(*object.Int); res.Value != 2 {
+		t.Errorf("expected and to return the last value, got %s", res)
+	}
+}
+
+func TestEval_Let(t *testing.T) {
+	res := run(t, `
+(defvar a 100)
+(let ((a 1) (b (+ a 1)))
+  (+ a b))`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 102 {
+		t.Errorf("expected 102, got %d", iVal.Value)
+	}
+}
+
+func TestEval_LetStar(t *testing.T) {
+	res := run(t, `
+(let* ((a 1) (b (+ a 1)) (a (* b 10)))
+  (+ a b))`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 22 {
+		t.Errorf("expected 22, got %d", iVal.Value)
+	}
+}
+
+func TestEval_LetDoesNotLeak(t *testing.T) {
+	program, err := parser.New(scanner.New(`(let ((a 1)) a) a`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Eval(program, object.NewContext()); err == nil {
+		t.Error("expected let bindings to be invisible outside of let")
+	}
+}
+
+func TestEval_Setq(t *testing.T) {
+	res := run(t, `
+(defvar counter 0)
+(defun bump (by) (setq counter (+ counter by)))
+(bump 2)
+(let ((x 1))
+  (set! x 40)
+  (bump x))
+counter`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 42 {
+		t.Errorf("expected 42, got %d", iVal.Value)
+	}
+}
+
+func TestEval_SetqErrors(t *testing.T) {
+	for _, source := range []string{
+		`(setq undefined-var 1)`,
+		`(defvar a 1) (defvar a 2)`,
+	} {
+		program, err := parser.New(scanner.New(source)).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Eval(program, object.NewContext()); err == nil {
+			t.Errorf("%s: expected an error", source)
+		}
+	}
+}
+
+func TestEval_While(t *testing.T) {
+	res := run(t, `
+(defvar i 0)
+(defvar sum 0)
+(while (< i 5)
+  (defvar step (+ i 1))
+  (setq sum (+ sum step))
+  (setq i step))
+sum`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 15 {
+		t.Errorf("expected 15, got %d", iVal.Value)
+	}
+}
+
+func TestEval_Dotimes(t *testing.T) {
+	res := run(t, `
+(defvar sum 0)
+(dotimes (i 4 (+ sum i))
+  (setq sum (+ sum i)))`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 10 {
+		t.Errorf("expected 10, got %d", iVal.Value)
+	}
+}
+
+func TestEval_Dolist(t *testing.T) {
+	res := run(t, `
+(defvar acc '())
+(dolist (x [1 2 3])
+  (setq acc (append acc (* x x))))
+(dolist (x '(4) acc)
+  (setq acc (append acc x)))`)
+
+	expected := &object.List{Elements: []object.Object{
+		&object.Int{Value: 1}, &object.Int{Value: 4}, &object.Int{Value: 9}, &object.Int{Value: 4},
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+}
+
+func TestEval_TailCallsRunInConstantStack(t *testing.T) {
+	// without tail calls elimination this recursion is way deeper
+	// than the stack limit allows, exceeding it crashes the test
+	defer debug.SetMaxStack(debug.SetMaxStack(4 << 20))
+
+	res := run(t, `
+(defun sum (n acc)
+  (if (= n 0)
+      acc
+      (sum (- n 1) (+ acc n))))
+(defun count-down (n)
+  (cond ((= n 0) "done")
+        (else (let ((m (- n 1))) (count-down m)))))
+(count-down 100000)
+(sum 100000 0)`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 5000050000 {
+		t.Errorf("expected 5000050000, got %d", iVal.Value)
+	}
+}
+
+func TestEval_NonTailRecursion(t *testing.T) {
+	res := run(t, `
+(defun fib (n)
+  (if (< n 2)
+      n
+      (+ (fib (- n 1)) (fib (- n 2)))))
+(fib 15)`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 610 {
+		t.Errorf("expected 610, got %d", iVal.Value)
+	}
+}
+
+func TestEval_Defmacro(t *testing.T) {
+	res := run(t, `
+(defmacro my-unless (c &rest body)
+  `+"`"+`(if ,c '() (let () ,@body)))
+(defmacro swap! (a b)
+  `+"`"+`(let ((tmp ,a)) (setq ,a ,b) (setq ,b tmp)))
+(defvar x 1)
+(defvar y 2)
+(swap! x y)
+(my-unless '() (setq x (* x 10)) (+ x y))`)
+
+	iVal := res.(*object.Int)
+	if iVal.Value != 21 {
+		t.Errorf("expected 21, got %d", iVal.Value)
+	}
+}
+
+func TestEval_QuasiQuote(t *testing.T) {
+	res := run(t, `
+(defvar xs '(2 3))
+`+"`"+`(1 ,@xs [1 ,(+ 2 2)] (b c))`)
+
+	expected := &object.List{Elements: []object.Object{
+		&object.Int{Value: 1},
+		&object.Int{Value: 2},
+		&object.Int{Value: 3},
+		&object.Vector{Elements: []object.Object{
+			&object.Int{Value: 1}, &object.Int{Value: 4},
+		}},
+		&object.List{Elements: []object.Object{
+			&object.Symbol{Name: "b"}, &object.Symbol{Name: "c"},
+		}},
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+}
+
+func TestEval_MacroDefinedEarlier(t *testing.T) {
+	ctx := object.NewContext()
+	for _, source := range []string{
+		"(defmacro twice (form) `(let () ,form ,form))",
+		"(defvar n 0)",
+		"(twice (setq n (+ n 1)))",
+	} {
+		prs := parser.New(scanner.New(source))
+		prs.SetMacroLookup(MacroLookup(ctx))
+		program, err := prs.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Eval(program, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := ctx.Get("n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.(*object.Int).Value != 2 {
+		t.Errorf("expected the form to be evaluated twice, got %s", n)
+	}
+}
+
+func TestEval_MacroExpandedOnce(t *testing.T) {
+	res := run(t, `
+(defvar expansions 0)
+(defvar q 0)
+(defmacro inc! (x)
+  (setq expansions (+ expansions 1))
+  `+"`"+`(setq ,x (+ ,x 1)))
+(dotimes (i 5) (inc! q))
+[expansions q]`)
+
+	expected := &object.Vector{Elements: []object.Object{
+		&object.Int{Value: 1}, &object.Int{Value: 5},
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected the macro to be expanded once, got %s", res)
+	}
+}
+
+func TestEval_RestParams(t *testing.T) {
+	res := run(t, `
+(defun count-args (first &rest others) (append others first))
+(count-args 1 2 3)`)
+
+	expected := &object.List{Elements: []object.Object{
+		&object.Int{Value: 2}, &object.Int{Value: 3}, &object.Int{Value: 1},
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+}
+
+func TestEval_ErrorPosition(t *testing.T) {
+	program, err := parser.New(scanner.NewFile("script.gl", `(defun f (x)
+  (+ x "a"))
+(f 1)`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Eval(program, object.NewContext())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	expected := "script.gl:2:3: __add__ expects positional argument #1 to be of type TInt, TString given"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestEval_StringRepeat(t *testing.T) {
+	if res := run(t, `(* "ab" 2 0)`); res.String() != "" {
+		t.Errorf("expected an empty string, got %q", res)
+	}
+
+	program, err := parser.New(scanner.New(`(* "ab" 2 "x")`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Eval(program, object.NewContext())
+	expected := `1:1: __mul__ expects positional argument #2 to be of type TInt, TString given`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestEval_ErrorKinds(t *testing.T) {
+	tests := map[string]diag.Kind{
+		`(print nope)`:                   diag.Unbound,
+		`(nope 1)`:                       diag.Unbound,
+		`(+ 1 "a")`:                      diag.Type,
+		`(/ 1 0)`:                        diag.Runtime,
+		`(numerator 1.5)`:                diag.Type,
+		`(* "ab" -1)`:                    diag.Runtime,
+		`(* "ab" 4294967296 4294967296)`: diag.Runtime,
+		`(defun f (x) x) (f 1 2)`:        diag.Arity,
+		`(defvar a 1) (defvar a 2)`:      diag.Runtime,
+		`(dotimes (i "3") (print i))`:    diag.Type,
+		`(setq not-defined-anywhere 1)`:  diag.Unbound,
+	}
+	for source, kind := range tests {
+		program, err := parser.New(scanner.New(source)).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(program, object.NewContext())
+		e, ok := err.(*diag.Error)
+		if !ok {
+			t.Errorf("%s: expected *diag.Error, got %#v", source, err)
+			continue
+		}
+		if e.Kind != kind || !e.Pos.IsValid() || e.Node == nil {
+			t.Errorf("%s: expected located %s, got %s at %s", source, kind, e.Kind, e.Pos)
+		}
+	}
+}
+
+func TestEval_Runes(t *testing.T) {
+	tests := map[string]object.Object{
+		`#\a`:                            &object.Rune{Value: 'a'},
+		`#\newline`:                      &object.Rune{Value: '\n'},
+		`#\λ`:                            &object.Rune{Value: 'λ'},
+		`#\u{3bb}`:                       &object.Rune{Value: 'λ'},
+		`(char->int #\A)`:                &object.Int{Value: 65},
+		`(int->char 955)`:                &object.Rune{Value: 'λ'},
+		`(upcase #\λ)`:                   &object.Rune{Value: 'Λ'},
+		`(downcase "AbC")`:               &object.String{Value: "abc"},
+		`(char-alphabetic? #\a)`:         &object.Bool{Value: true},
+		`(char-numeric? #\a)`:            &object.Bool{Value: false},
+		`(char-whitespace? #\space)`:     &object.Bool{Value: true},
+		`(char-upper-case? #\A)`:         &object.Bool{Value: true},
+		`(char-lower-case? #\A)`:         &object.Bool{Value: false},
+		`(< #\a #\b)`:                    &object.Bool{Value: true},
+		"(defmacro r () #\\x) (r)":       &object.Rune{Value: 'x'},
+		`(map char->int '(#\( #\)))`:     &object.List{Elements: []object.Object{&object.Int{Value: 40}, &object.Int{Value: 41}}},
+		`(char->int (int->char 128512))`: &object.Int{Value: 128512},
+	}
+
+	for source, expected := range tests {
+		if res := run(t, source); !reflect.DeepEqual(res, expected) {
+			t.Errorf("%s: expected %v, got %v", source, expected, res)
+		}
+	}
+}
+
+func TestEval_NumericLiterals(t *testing.T) {
+	res := run(t, "(+ -5 0x10 0b11 1_000)")
+	if !reflect.DeepEqual(res, &object.Int{Value: 1014}) {
+		t.Errorf("expected 1014, got %v", res)
+	}
+}
+
+func TestEval_IdentifiersWithDigitsAndPunctuation(t *testing.T) {
+	res := run(t, `
+(defvar vec3 [1 2 3])
+(defun empty? (xs) (= 0 0))
+(defun x->y (x) (* x 2))
+(defvar total% 0)
+(set! total% (x->y 21))
+total%`)
+	if !reflect.DeepEqual(res, &object.Int{Value: 42}) {
+		t.Errorf("expected 42, got %v", res)
+	}
+}
+
+func TestEval_Keywords(t *testing.T) {
+	res := run(t, `(defvar k :name) k`)
+	if res != object.NewKeyword("name") {
+		t.Errorf("expected the interned :name, got %#v", res)
+	}
+
+	tests := map[string]object.Object{
+		`(= :a :a)`:                     &object.Bool{Value: true},
+		`(= :a :b)`:                     &object.Bool{Value: false},
+		`(/= :a :b)`:                    &object.Bool{Value: true},
+		`'(:a 1)`:                       &object.List{Elements: []object.Object{object.NewKeyword("a"), &object.Int{Value: 1}}},
+		"(defmacro k () :x) (= (k) :x)": &object.Bool{Value: true},
+	}
+	for source, expected := range tests {
+		if res := run(t, source); !reflect.DeepEqual(res, expected) {
+			t.Errorf("%s: expected %v, got %v", source, expected, res)
+		}
+	}
+}
+
+func TestEval_Quote(t *testing.T) {
+	sym := func(name string) object.Object { return &object.Symbol{Name: name} }
+	list := func(els ...object.Object) object.Object { return &object.List{Elements: els} }
+
+	tests := map[string]object.Object{
+		`'x`:            sym("x"),
+		`(quote x)`:     sym("x"),
+		`'1`:            &object.Int{Value: 1},
+		`'[1 2]`:        &object.Vector{Elements: []object.Object{&object.Int{Value: 1}, &object.Int{Value: 2}}},
+		`'[a b]`:        &object.Vector{Elements: []object.Object{sym("a"), sym("b")}},
+		`'(a (b c))`:    list(sym("a"), list(sym("b"), sym("c"))),
+		`'(+ 1 2)`:      list(sym("+"), &object.Int{Value: 1}, &object.Int{Value: 2}),
+		`(quote (a b))`: list(sym("a"), sym("b")),
+		`''x`:           list(sym("quote"), sym("x")),
+		`'()`:           &object.List{Elements: []object.Object{}},
+	}
+	for source, expected := range tests {
+		if res := run(t, source); !reflect.DeepEqual(res, expected) {
+			t.Errorf("%s: expected %v, got %v", source, expected, res)
+		}
+	}
+}
+
+func TestEval_QuotedVectorTypes(t *testing.T) {
+	for _, source := range []string{`[1 "a"]`, `'[1 "a" x]`, "`[1 ,\"a\"]", `'(1 [2 x])`} {
+		program, err := parser.New(scanner.New(source)).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(program, object.NewContext())
+		if e, ok := err.(*diag.Error); !ok || e.Kind != diag.Type {
+			t.Errorf("%s: expected a type error, got %v", source, err)
+		}
+	}
+}
+
+func TestEval_QuotedListRendering(t *testing.T) {
+	tests := map[string]string{
+		`'(a (b c))`:              `'(a (b c))`,
+		`'(a '(b c))`:             `'(a (quote (b c)))`,
+		`'(a [(b) (1)] {:k (c)})`: `'(a [(b) (1)] {:k (c)})`,
+		`'()`:                     `'()`,
+		`['(a (b))]`:              `['(a (b))]`,
+	}
+	for source, expected := range tests {
+		if res := run(t, source); res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, res)
+		}
+	}
+}
+
+func TestEval_Maps(t *testing.T) {
+	tests := map[string]string{
+		`{:b 2 :a (+ 1 0)}`:                           `{:a 1 :b 2}`,
+		`'{:a x}`:                                     `{:a x}`,
+		`(get {:a 1} :a)`:                             `1`,
+		`(get {:a 1} :b 0)`:                           `0`,
+		`(assoc {:a 1} :b 2 :a 3)`:                    `{:a 3 :b 2}`,
+		`(dissoc {:a 1 :b 2 :c 3} :a :c)`:             `{:b 2}`,
+		`(keys {"b" 1 "a" 2})`:                        `'(a b)`,
+		`(vals {"b" 1 "a" 2})`:                        `'(2 1)`,
+		`(contains? {#\a 1} #\a)`:                     `true`,
+		`(contains? {#\a 1} #\b)`:                     `false`,
+		`(merge {:a 1 :b 1} {:b 2} {:c 3})`:           `{:a 1 :b 2 :c 3}`,
+		`(defvar m {:a 1}) (assoc m :a 2) (get m :a)`: `1`,
+		"(defmacro m () {:a 1}) (get (m) :a)":         `1`,
+	}
+	for source, expected := range tests {
+		if res := run(t, source); res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, res)
+		}
+	}
+}
+
+func TestEval_MapCollectionKeys(t *testing.T) {
+	res := run(t, `(get {'(1 2) :list [1 2] :vector} (append '(1) 2))`)
+	if res != object.NewKeyword("list") {
+		t.Errorf("expected :list, got %v", res)
+	}
+}
+
+func TestEval_EqualAndEq(t *testing.T) {
+	tests := map[string]bool{
+		`(equal? '(1 [2 3] "a") '(1 [2 3] "a"))`:      true,
+		`(equal? {:a '(1)} {:a '(1)} {:a '(1)})`:      true,
+		`(equal? '(1) [1])`:                           false,
+		`(equal? 1 1.0)`:                              false,
+		`(equal? "a" "b")`:                            false,
+		`(eq? 1 1)`:                                   true,
+		`(eq? :a :a)`:                                 true,
+		`(eq? 'a 'a)`:                                 true,
+		`(eq? '(1) '(1))`:                             false,
+		`(defvar xs '(1)) (eq? xs xs)`:                true,
+		`(defun f () 1) (eq? f f)`:                    true,
+		`(eq? (lambda () 1) (lambda () 1))`:           false,
+		`(eq? + +)`:                                   true,
+		`(equal? (map (lambda (x) x) '(1 2)) '(1 2))`: true,
+	}
+	for source, expected := range tests {
+		res := run(t, source)
+		if !object.Equal(res, &object.Bool{Value: expected}) {
+			t.Errorf("%s: expected %t, got %v", source, expected, res)
+		}
+	}
+}
+
+func TestEval_NilAndBoolLiterals(t *testing.T) {
+	tests := map[string]string{
+		`nil`:                                   `nil`,
+		`true`:                                  `true`,
+		`(not false)`:                           `true`,
+		`(if nil 1 2)`:                          `2`,
+		`(print (print 1))`:                     `nil`,
+		`(equal? (print) nil)`:                  `true`,
+		`(eq? nil nil)`:                         `true`,
+		`(get {:a nil} :b)`:                     `nil`,
+		`(contains? {nil 1} nil)`:               `true`,
+		`'(nil true false)`:                     `'(nil true false)`,
+		`(defvar x 1)`:                          `x`,
+		`(defun f () 1)`:                        `f`,
+		`(while false 1)`:                       `nil`,
+		`(dolist (x '(1)) x)`:                   `nil`,
+		`(let ((x nil)) (or x false))`:          `false`,
+		"(defmacro m () '(if false 1 nil)) (m)": `nil`,
+		"(defmacro n (x) `(not ,x)) (n true)":   `false`,
+	}
+	for source, expected := range tests {
+		if res := run(t, source); res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, res)
+		}
+	}
+}
+
+func TestEval_AppendLeavesListIntact(t *testing.T) {
+	tests := map[string]string{
+		`(defvar b (append (append (append '() 1) 2) 3)) (defvar c (append b 4)) (defvar d (append b 5)) [b c d]`: `['(1 2 3) '(1 2 3 4) '(1 2 3 5)]`,
+		`(defvar k (append (append '() 1) 2)) (defvar m {k :k}) (append k 3) (get m '(1 2))`:                      `:k`,
+	}
+	for source, expected := range tests {
+		if res := run(t, source); res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, res)
+		}
+	}
+}
+
+func TestEval_NilAsEmptyList(t *testing.T) {
+	tests := map[string]string{
+		`(dolist (x nil) (undefined-fn))`:    `nil`,
+		`(dolist (x nil 5))`:                 `5`,
+		`(map + nil nil)`:                    `'()`,
+		`(map + '(1 2) nil)`:                 `'()`,
+		`(append nil 1 2)`:                   `'(1 2)`,
+		"(defmacro m () `(+ 1 ,@nil 2)) (m)": `3`,
+	}
+	for source, expected := range tests {
+		if res := run(t, source); res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, res)
+		}
+	}
+}
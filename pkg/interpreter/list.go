@@ -4,20 +4,70 @@ import (
 	"github.com/pmukhin/glisp/pkg/object"
 )
 
+// glispAppend returns a new list of elements of a list followed
+// by the rest of args, nil is taken as an empty list
 func glispAppend(args ...object.Object) (object.Object, error) {
 	if len(args) < 2 {
 		return nil, makeArgsLenErr("append", 2, len(args))
 	}
-	if args[0].Type() != object.TList {
+	var elements []object.Object
+	switch list := args[0].(type) {
+	case *object.List:
+		elements = list.Elements
+	case *object.NilType:
+	default:
 		return nil, makeUnexpectedTypeErr("append", 0,
 			object.TList, args[0].Type())
 	}
-	list := args[0].(*object.List)
-	newList := &object.List{Elements: list.Elements}
 
-	for _, argument := range args[1:] {
-		newList.Elements = append(newList.Elements, argument)
+	// the list is a value shared by its holders, it's never written to
+	newElements := make([]object.Object, 0, len(elements)+len(args)-1)
+	newElements = append(newElements, elements...)
+	newElements = append(newElements, args[1:]...)
+
+	return &object.List{Elements: newElements}, nil
+}
+
+// glispMap applies a function to elements of lists or vectors
+// taken pairwise, the result is as long as the shortest collection.
+// nil is taken as an empty list
+func glispMap(args ...object.Object) (object.Object, error) {
+	if len(args) < 2 {
+		return nil, makeArgsLenErr("map", 2, len(args))
 	}
 
-	return newList, nil
+	colls := make([][]object.Object, len(args)-1)
+	minLen := -1
+	for i, arg := range args[1:] {
+		switch coll := arg.(type) {
+		case *object.List:
+			colls[i] = coll.Elements
+		case *object.Vector:
+			colls[i] = coll.Elements
+		case *object.NilType:
+		default:
+			return nil, makeUnexpectedTypeErr("map", i+1, object.TList, arg.Type())
+		}
+		if minLen == -1 || len(colls[i]) < minLen {
+			minLen = len(colls[i])
+		}
+	}
+
+	elements := make([]object.Object, minLen)
+	for i := 0; i < minLen; i++ {
+		fnArgs := make([]object.Object, len(colls))
+		for j, coll := range colls {
+			fnArgs[j] = coll[i]
+		}
+		res, err := apply(args[0], fnArgs)
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = res
+	}
+
+	if args[1].Type() == object.TVector {
+		return &object.Vector{Elements: elements}, nil
+	}
+	return &object.List{Elements: elements}, nil
 }
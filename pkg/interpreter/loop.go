@@ -0,0 +1,101 @@
+package interpreter
+
+import (
+	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/diag"
+	"github.com/pmukhin/glisp/pkg/object"
+)
+
+// evalWhile evaluates body in a fresh scope while the condition holds
+func evalWhile(node ast.Node, ctx object.Context) (object.Object, error) {
+	whileExpr := node.(*ast.WhileExpression)
+	for {
+		cond, err := Eval(whileExpr.Condition, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(cond) {
+			return object.Nil, nil
+		}
+		if _, err := resolve(evalBody(whileExpr.Body, ctx.NewChild())); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// evalDotimes evaluates body binding the index from 0 to count - 1
+func evalDotimes(node ast.Node, ctx object.Context) (object.Object, error) {
+	dotimesExpr := node.(*ast.DotimesExpression)
+	count, err := Eval(dotimesExpr.Count, ctx)
+	if err != nil {
+		return nil, err
+	}
+	iCount, ok := count.(*object.Int)
+	if !ok {
+		return nil, diag.Errorf(diag.Type, "dotimes expects count to be of type %s, %s given",
+			object.TInt, count.Type())
+	}
+
+	for i := int64(0); i < iCount.Value; i++ {
+		if err := evalLoopIteration(dotimesExpr.Var, &object.Int{Value: i},
+			dotimesExpr.Body, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return evalLoopResult(dotimesExpr.Var, count, dotimesExpr.Result, ctx)
+}
+
+// evalDolist evaluates body binding every element of a list or a vector,
+// nil is taken as an empty list
+func evalDolist(node ast.Node, ctx object.Context) (object.Object, error) {
+	dolistExpr := node.(*ast.DolistExpression)
+	coll, err := Eval(dolistExpr.List, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []object.Object
+	switch c := coll.(type) {
+	case *object.List:
+		elements = c.Elements
+	case *object.Vector:
+		elements = c.Elements
+	case *object.NilType:
+	default:
+		return nil, diag.Errorf(diag.Type, "dolist expects a list or a vector, %s given", coll.Type())
+	}
+
+	for _, el := range elements {
+		if err := evalLoopIteration(dolistExpr.Var, el, dolistExpr.Body, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return evalLoopResult(dolistExpr.Var, object.Nil, dolistExpr.Result, ctx)
+}
+
+// evalLoopIteration evaluates body in a fresh scope holding the loop variable
+func evalLoopIteration(v *ast.IdentifierExpression, value object.Object,
+	body []ast.Expression, ctx object.Context) error {
+	env := ctx.NewChild()
+	if err := env.Set(v.Value, value); err != nil {
+		return err
+	}
+	_, err := resolve(evalBody(body, env))
+	return err
+}
+
+// evalLoopResult evaluates the optional result form,
+// the loop variable is bound to its final value meanwhile
+func evalLoopResult(v *ast.IdentifierExpression, final object.Object,
+	result ast.Expression, ctx object.Context) (object.Object, error) {
+	if result == nil {
+		return object.Nil, nil
+	}
+	env := ctx.NewChild()
+	if err := env.Set(v.Value, final); err != nil {
+		return nil, err
+	}
+	return Eval(result, env)
+}
@@ -0,0 +1,286 @@
+package interpreter
+
+import (
+	"strconv"
+
+	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/diag"
+	"github.com/pmukhin/glisp/pkg/object"
+	"github.com/pmukhin/glisp/pkg/parser"
+	"github.com/pmukhin/glisp/pkg/token"
+)
+
+// MacroLookup tells the parser which names are macros in a given context
+func MacroLookup(ctx object.Context) func(name string) bool {
+	return func(name string) bool {
+		val, err := ctx.Get(name)
+		if err != nil {
+			return false
+		}
+		_, ok := val.(*object.Macro)
+		return ok
+	}
+}
+
+// evalDefmacro defines a macro in a given context
+func evalDefmacro(node ast.Node, ctx object.Context) (object.Object, error) {
+	defmacroExpr := node.(*ast.DefmacroExpression)
+	macro := &object.Macro{
+		Name:   defmacroExpr.Name.Value,
+		Params: paramNames(defmacroExpr.Params),
+		Rest:   restName(defmacroExpr.Rest),
+		Body:   defmacroExpr.Body,
+		Env:    ctx,
+	}
+	if err := ctx.Set(macro.Name, macro); err != nil {
+		return nil, err
+	}
+
+	return &object.Symbol{Name: macro.Name}, nil
+}
+
+// evalMacroCall expands a macro call and evaluates the expansion
+// in place of it, the expansion stays in tail position. A call is
+// expanded only once, later evaluations reuse the expansion
+func evalMacroCall(node ast.Node, ctx object.Context) (object.Object, error) {
+	mce := node.(*ast.MacroCallExpression)
+	if mce.Expansion == nil {
+		expansion, err := expandMacro(mce, ctx)
+		if err != nil {
+			return nil, err
+		}
+		mce.Expansion = expansion
+	}
+	return evalTail(mce.Expansion, ctx)
+}
+
+// expandMacro calls a macro with its args taken as data
+// and parses the form it returns
+func expandMacro(mce *ast.MacroCallExpression, ctx object.Context) (ast.Expression, error) {
+	val, err := ctx.Get(mce.Name.Value)
+	if err != nil {
+		return nil, diag.Errorf(diag.Unbound, "macro `%s` is not defined", mce.Name.Value)
+	}
+	macro, ok := val.(*object.Macro)
+	if !ok {
+		return nil, diag.Errorf(diag.Type, "%s is not a macro", mce.Name.Value)
+	}
+
+	args := make([]object.Object, len(mce.Args))
+	for i, arg := range mce.Args {
+		if args[i], err = quoteDatum(arg, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	env, err := bindArgs(macro.Name, macro.Params, macro.Rest, args, macro.Env)
+	if err != nil {
+		return nil, err
+	}
+	form, err := resolve(evalBody(macro.Body, env))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := objectToTokens(form, mce.Token.Pos, nil)
+	if err != nil {
+		return nil, diag.Errorf(diag.Type, "macro %s expansion: %s", macro.Name, err)
+	}
+	prs := parser.New(&tokenStream{tokens: tokens})
+	prs.SetMacroLookup(MacroLookup(ctx))
+
+	program, err := prs.Parse()
+	if err != nil {
+		msg := err.Error()
+		if l, ok := err.(diag.List); ok && len(l) > 0 {
+			msg = l[0].Msg
+		}
+		return nil, diag.Errorf(diag.Syntax, "macro %s expansion: %s", macro.Name, msg)
+	}
+	if len(program.Statements) != 1 {
+		return nil, diag.Errorf(diag.Syntax, "macro %s expansion: expected a single form, got %d",
+			macro.Name, len(program.Statements))
+	}
+
+	return program.Statements[0].(*ast.ExpressionStatement).Expression, nil
+}
+
+// evalQuote returns the datum quoted with no evaluation
+func evalQuote(node ast.Node, ctx object.Context) (object.Object, error) {
+	return quoteDatum(node.(*ast.QuoteExpression).Value, ctx)
+}
+
+// evalQuasiQuote builds data from a template evaluating unquoted parts
+func evalQuasiQuote(node ast.Node, ctx object.Context) (object.Object, error) {
+	return quoteDatum(node.(*ast.QuasiQuoteExpression).Value, ctx)
+}
+
+// quoteDatum turns a form parsed as data into an object
+func quoteDatum(node ast.Node, ctx object.Context) (object.Object, error) {
+	switch datum := node.(type) {
+	case *ast.IdentifierExpression:
+		return &object.Symbol{Name: datum.Value}, nil
+	case *ast.IntegerExpression, *ast.BigIntegerExpression, *ast.FloatExpression,
+		*ast.StringExpression, *ast.RuneExpression, *ast.KeywordExpression,
+		*ast.NilExpression, *ast.BoolExpression:
+		return Eval(datum, ctx)
+	case *ast.ListExpression:
+		elements, err := quoteDatumList(datum.Elements, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &object.List{Elements: elements}, nil
+	case *ast.VectorExpression:
+		elements, err := quoteDatumList(datum.Elements, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkVectorElements(elements); err != nil {
+			return nil, err
+		}
+		return &object.Vector{Elements: elements}, nil
+	case *ast.MapExpression:
+		keys, err := quoteDatumList(datum.Keys, ctx)
+		if err != nil {
+			return nil, err
+		}
+		values, err := quoteDatumList(datum.Values, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return makeMap(keys, values)
+	case *ast.UnquoteExpression:
+		return Eval(datum.Value, ctx)
+	case *ast.UnquoteSplicingExpression:
+		return nil, diag.Errorf(diag.Syntax, ",@ is allowed only inside of a list or a vector")
+	default:
+		return nil, diag.Errorf(diag.Syntax, "can not quote %s", node.Type())
+	}
+}
+
+// quoteDatumList quotes elements splicing the ones marked with ,@
+func quoteDatumList(nodes []ast.Expression, ctx object.Context) ([]object.Object, error) {
+	elements := make([]object.Object, 0, len(nodes))
+	for _, node := range nodes {
+		splice, ok := node.(*ast.UnquoteSplicingExpression)
+		if !ok {
+			el, err := quoteDatum(node, ctx)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, el)
+			continue
+		}
+
+		val, err := Eval(splice.Value, ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch coll := val.(type) {
+		case *object.List:
+			elements = append(elements, coll.Elements...)
+		case *object.Vector:
+			elements = append(elements, coll.Elements...)
+		case *object.NilType:
+			// nil splices nothing just like an empty list
+		default:
+			return nil, diag.Errorf(diag.Type, ",@ expects a list or a vector, %s given", val.Type())
+		}
+	}
+
+	return elements, nil
+}
+
+// quoteForms are heads of lists written back with a reader shorthand
+var quoteForms = map[string]token.Type{
+	"quote":            token.SingleQuote,
+	"quasiquote":       token.Backquote,
+	"unquote":          token.Comma,
+	"unquote-splicing": token.CommaAt,
+}
+
+// objectToTokens writes data back as tokens for the parser,
+// every token is placed at pos which is where the macro has been called
+func objectToTokens(obj object.Object, pos token.Position, tokens []token.Token) ([]token.Token, error) {
+	switch o := obj.(type) {
+	case *object.Symbol:
+		return append(tokens, token.New(token.Identifier, pos, o.Name)), nil
+	case *object.Int:
+		return append(tokens, token.New(token.Integer, pos,
+			strconv.FormatInt(o.Value, 10))), nil
+	case *object.BigInt:
+		return append(tokens, token.New(token.Integer, pos, o.Value.String())), nil
+	case *object.Ratio:
+		// there's no ratio literal, it's written as a division
+		return append(tokens,
+			token.New(token.ParenOp, pos),
+			token.New(token.Identifier, pos, "/"),
+			token.New(token.Integer, pos, o.Value.Num().String()),
+			token.New(token.Integer, pos, o.Value.Denom().String()),
+			token.New(token.ParenCl, pos)), nil
+	case *object.Float:
+		return append(tokens, token.New(token.Float, pos,
+			strconv.FormatFloat(o.Value, 'g', -1, 64))), nil
+	case *object.String:
+		return append(tokens, token.New(token.String, pos, o.Value)), nil
+	case *object.Rune:
+		return append(tokens, token.New(token.Rune, pos, string(o.Value))), nil
+	case *object.Keyword:
+		return append(tokens, token.New(token.Keyword, pos, o.Name)), nil
+	case *object.List:
+		if len(o.Elements) == 2 {
+			if head, ok := o.Elements[0].(*object.Symbol); ok {
+				if typ, ok := quoteForms[head.Name]; ok {
+					return objectToTokens(o.Elements[1], pos, append(tokens, token.New(typ, pos)))
+				}
+			}
+		}
+		return elementsToTokens(o.Elements, pos,
+			append(tokens, token.New(token.ParenOp, pos)), token.New(token.ParenCl, pos))
+	case *object.Vector:
+		return elementsToTokens(o.Elements, pos,
+			append(tokens, token.New(token.BracketOp, pos)), token.New(token.BracketCl, pos))
+	case *object.Map:
+		elements := make([]object.Object, 0, 2*o.Len())
+		for _, pair := range o.Pairs() {
+			elements = append(elements, pair.Key, pair.Value)
+		}
+		return elementsToTokens(elements, pos,
+			append(tokens, token.New(token.BraceOp, pos)), token.New(token.BraceCl, pos))
+	case *object.NilType, *object.Bool:
+		// written as nil, true or false
+		return append(tokens, token.New(token.Identifier, pos, o.String())), nil
+	default:
+		return nil, diag.Errorf(diag.Type, "%s can not be a part of code", obj.Type())
+	}
+}
+
+func elementsToTokens(elements []object.Object, pos token.Position,
+	tokens []token.Token, closing token.Token) ([]token.Token, error) {
+	var err error
+	for _, el := range elements {
+		if tokens, err = objectToTokens(el, pos, tokens); err != nil {
+			return nil, err
+		}
+	}
+	return append(tokens, closing), nil
+}
+
+// tokenStream feeds the parser with tokens of a macro expansion
+type tokenStream struct {
+	tokens []token.Token
+	offset int
+}
+
+// Next ...
+func (ts *tokenStream) Next() token.Token {
+	if ts.offset >= len(ts.tokens) {
+		var pos token.Position
+		if len(ts.tokens) > 0 {
+			pos = ts.tokens[len(ts.tokens)-1].Pos
+		}
+		return token.New(token.EOF, pos, "")
+	}
+	ts.offset++
+	return ts.tokens[ts.offset-1]
+}
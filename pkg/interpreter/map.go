@@ -0,0 +1,162 @@
+package interpreter
+
+import (
+	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/diag"
+	"github.com/pmukhin/glisp/pkg/object"
+)
+
+// evalMap evaluates keys and values of a map literal,
+// a key given twice keeps the last value
+func evalMap(node ast.Node, ctx object.Context) (object.Object, error) {
+	mapExpr := node.(*ast.MapExpression)
+	keys := make([]object.Object, len(mapExpr.Keys))
+	values := make([]object.Object, len(mapExpr.Values))
+
+	for i, key := range mapExpr.Keys {
+		oKey, err := Eval(key, ctx)
+		if err != nil {
+			return nil, err
+		}
+		oValue, err := Eval(mapExpr.Values[i], ctx)
+		if err != nil {
+			return nil, err
+		}
+		keys[i], values[i] = oKey, oValue
+	}
+
+	return makeMap(keys, values)
+}
+
+func makeMap(keys, values []object.Object) (object.Object, error) {
+	if len(keys) != len(values) {
+		return nil, diag.Errorf(diag.Runtime, "map expects as many keys as values, %d and %d given",
+			len(keys), len(values))
+	}
+
+	m := object.NewMap()
+	for i, key := range keys {
+		m.Set(key, values[i])
+	}
+	return m, nil
+}
+
+func extractMapArg(funName string, pos int, args []object.Object) (*object.Map, error) {
+	m, ok := args[pos].(*object.Map)
+	if !ok {
+		return nil, makeUnexpectedTypeErr(funName, pos, object.TMap, args[pos].Type())
+	}
+	return m, nil
+}
+
+// glispGet returns a value of a key of a map or
+// an optional default if the map has no such key
+func glispGet(args ...object.Object) (object.Object, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, diag.Errorf(diag.Arity, "get expects 2 or 3 args, %d given", len(args))
+	}
+	m, err := extractMapArg("get", 0, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if val, ok := m.Get(args[1]); ok {
+		return val, nil
+	}
+	if len(args) == 3 {
+		return args[2], nil
+	}
+	return object.Nil, nil
+}
+
+// glispAssoc returns a map having keys set to values given pairwise
+func glispAssoc(args ...object.Object) (object.Object, error) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return nil, diag.Errorf(diag.Arity, "assoc expects a map and pairs of keys and values, %d args given",
+			len(args))
+	}
+	m, err := extractMapArg("assoc", 0, args)
+	if err != nil {
+		return nil, err
+	}
+
+	res := m.Copy()
+	for i := 1; i < len(args); i += 2 {
+		res.Set(args[i], args[i+1])
+	}
+	return res, nil
+}
+
+// glispDissoc returns a map with no given keys
+func glispDissoc(args ...object.Object) (object.Object, error) {
+	if len(args) < 1 {
+		return nil, makeArgsLenErr("dissoc", 1, len(args))
+	}
+	m, err := extractMapArg("dissoc", 0, args)
+	if err != nil {
+		return nil, err
+	}
+
+	res := m.Copy()
+	for _, key := range args[1:] {
+		res.Delete(key)
+	}
+	return res, nil
+}
+
+// makeMapProjection makes a builtin listing either keys or values of a map
+func makeMapProjection(funName string, project func(object.MapPair) object.Object) internalFunc {
+	return func(args ...object.Object) (object.Object, error) {
+		if len(args) != 1 {
+			return nil, diag.Errorf(diag.Arity, "%s expects exactly 1 arg, %d given", funName, len(args))
+		}
+		m, err := extractMapArg(funName, 0, args)
+		if err != nil {
+			return nil, err
+		}
+
+		elements := make([]object.Object, 0, m.Len())
+		for _, pair := range m.Pairs() {
+			elements = append(elements, project(pair))
+		}
+		return &object.List{Elements: elements}, nil
+	}
+}
+
+// glispContains tells if a map has a key
+func glispContains(args ...object.Object) (object.Object, error) {
+	if len(args) != 2 {
+		return nil, diag.Errorf(diag.Arity, "contains? expects exactly 2 args, %d given", len(args))
+	}
+	m, err := extractMapArg("contains?", 0, args)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok := m.Get(args[1])
+	return &object.Bool{Value: ok}, nil
+}
+
+// glispMerge merges maps into a new one, keys of latter maps win
+func glispMerge(args ...object.Object) (object.Object, error) {
+	if len(args) < 1 {
+		return nil, makeArgsLenErr("merge", 1, len(args))
+	}
+
+	res := object.NewMap()
+	for i := range args {
+		m, err := extractMapArg("merge", i, args)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range m.Pairs() {
+			res.Set(pair.Key, pair.Value)
+		}
+	}
+	return res, nil
+}
+
+var (
+	glispKeys = makeMapProjection("keys", func(pair object.MapPair) object.Object { return pair.Key })
+	glispVals = makeMapProjection("vals", func(pair object.MapPair) object.Object { return pair.Value })
+)
@@ -0,0 +1,77 @@
+package interpreter
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pmukhin/glisp/pkg/diag"
+	"github.com/pmukhin/glisp/pkg/object"
+)
+
+// charToInt returns the code point of a rune
+func charToInt(args ...object.Object) (object.Object, error) {
+	r, err := extractRuneArg("char->int", args)
+	if err != nil {
+		return nil, err
+	}
+	return &object.Int{Value: int64(r)}, nil
+}
+
+// intToChar returns the rune of a code point
+func intToChar(args ...object.Object) (object.Object, error) {
+	if len(args) != 1 {
+		return nil, diag.Errorf(diag.Arity, "int->char expects exactly 1 arg, %d given", len(args))
+	}
+	i, ok := args[0].(*object.Int)
+	if !ok {
+		return nil, makeUnexpectedTypeErr("int->char", 0, object.TInt, args[0].Type())
+	}
+	if i.Value < 0 || i.Value > unicode.MaxRune || (i.Value >= 0xd800 && i.Value <= 0xdfff) {
+		return nil, diag.Errorf(diag.Runtime, "int->char: %d is not a valid code point", i.Value)
+	}
+	return &object.Rune{Value: rune(i.Value)}, nil
+}
+
+// makeCaseMapping makes a builtin mapping case of a rune or a string
+func makeCaseMapping(name string, runeFn func(rune) rune, strFn func(string) string) internalFunc {
+	return func(args ...object.Object) (object.Object, error) {
+		if len(args) != 1 {
+			return nil, diag.Errorf(diag.Arity, "%s expects exactly 1 arg, %d given", name, len(args))
+		}
+		switch arg := args[0].(type) {
+		case *object.Rune:
+			return &object.Rune{Value: runeFn(arg.Value)}, nil
+		case *object.String:
+			return &object.String{Value: strFn(arg.Value)}, nil
+		default:
+			return nil, makeFunNotDefErr(name, arg.Type())
+		}
+	}
+}
+
+// makeRunePredicate makes a builtin telling if a rune belongs to a class
+func makeRunePredicate(name string, pred func(rune) bool) internalFunc {
+	return func(args ...object.Object) (object.Object, error) {
+		r, err := extractRuneArg(name, args)
+		if err != nil {
+			return nil, err
+		}
+		return &object.Bool{Value: pred(r)}, nil
+	}
+}
+
+func extractRuneArg(funName string, args []object.Object) (rune, error) {
+	if len(args) != 1 {
+		return 0, diag.Errorf(diag.Arity, "%s expects exactly 1 arg, %d given", funName, len(args))
+	}
+	r, ok := args[0].(*object.Rune)
+	if !ok {
+		return 0, makeUnexpectedTypeErr(funName, 0, object.TRune, args[0].Type())
+	}
+	return r.Value, nil
+}
+
+var (
+	upcase   = makeCaseMapping("upcase", unicode.ToUpper, strings.ToUpper)
+	downcase = makeCaseMapping("downcase", unicode.ToLower, strings.ToLower)
+)
@@ -1,41 +1,74 @@
 package object
 
 import (
-	"fmt"
+	"github.com/pmukhin/glisp/pkg/diag"
 )
 
-// Context is a variable container
+// Context is a variable container, contexts are chained
+// from the innermost scope up to the global one
 type Context interface {
 	Set(varName string, object Object) error
 	Get(varName string) (Object, error)
+	// Assign rebinds a variable in the closest scope defining it
+	Assign(varName string, object Object) error
+	// NewChild creates a scope enclosed by this one
+	NewChild() Context
+	// Parent returns the enclosing scope or nil for the global one
+	Parent() Context
 }
 
 // context is a native implementation of Context
 // it holds varmap as a map[string]object.Object
 type context struct {
 	varmap map[string]Object
+	parent Context
 }
 
 // Set sets variable in current context
-// returns error is variable had been set already
+// returns error is variable had been set already in this very scope,
+// shadowing a variable of an enclosing scope is allowed
 func (c *context) Set(varName string, object Object) error {
 	if _, ok := c.varmap[varName]; ok {
 		// redefinition!
-		return fmt.Errorf("redifinition of variable %s", varName)
+		return diag.Errorf(diag.Runtime, "redifinition of variable %s", varName)
 	}
 	c.varmap[varName] = object
 	return nil
 }
 
-// Set gets variable from current context
+// Get gets variable from current context or the closest enclosing one
 // returns error is variable has not ever been set
 func (c *context) Get(varName string) (Object, error) {
-	if val, ok := c.varmap[varName]; !ok {
-		// redefinition!
-		return nil, fmt.Errorf("undefined variable %s", varName)
-	} else {
+	if val, ok := c.varmap[varName]; ok {
 		return val, nil
 	}
+	if c.parent != nil {
+		return c.parent.Get(varName)
+	}
+	return nil, diag.Errorf(diag.Unbound, "undefined variable %s", varName)
+}
+
+// Assign updates variable in the closest context it has been set in
+// returns error is variable has not ever been set
+func (c *context) Assign(varName string, object Object) error {
+	if _, ok := c.varmap[varName]; ok {
+		c.varmap[varName] = object
+		return nil
+	}
+	if c.parent != nil {
+		return c.parent.Assign(varName, object)
+	}
+	return diag.Errorf(diag.Unbound, "assignment to undefined variable %s", varName)
+}
+
+// NewChild ...
+func (c *context) NewChild() Context {
+	return &context{varmap: make(map[string]Object), parent: c}
+}
+
+// Parent ...
+func (c *context) Parent() Context {
+	return c.parent
 }
 
 // NewContext is Context constructor
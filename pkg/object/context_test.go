@@ -0,0 +1,86 @@
+package object
+
+import (
+	"testing"
+)
+
+func TestContext_ChildSeesParent(t *testing.T) {
+	global := NewContext()
+	global.Set("a", &Int{Value: 1})
+
+	child := global.NewChild().NewChild()
+	val, err := child.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.(*Int).Value != 1 {
+		t.Errorf("expected 1, got %s", val)
+	}
+	if child.Parent().Parent() != global {
+		t.Error("expected parent chain to end with the global context")
+	}
+	if global.Parent() != nil {
+		t.Error("global context must have no parent")
+	}
+}
+
+func TestContext_Shadowing(t *testing.T) {
+	global := NewContext()
+	global.Set("a", &Int{Value: 1})
+
+	child := global.NewChild()
+	if err := child.Set("a", &Int{Value: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	inner, _ := child.Get("a")
+	outer, _ := global.Get("a")
+	if inner.(*Int).Value != 2 || outer.(*Int).Value != 1 {
+		t.Errorf("expected shadowed 2 and outer 1, got %s and %s", inner, outer)
+	}
+}
+
+func TestContext_RedefinitionInSameScope(t *testing.T) {
+	child := NewContext().NewChild()
+	child.Set("a", &Int{Value: 1})
+
+	if err := child.Set("a", &Int{Value: 2}); err == nil {
+		t.Error("expected redefinition error")
+	}
+}
+
+func TestContext_Undefined(t *testing.T) {
+	if _, err := NewContext().NewChild().Get("nope"); err == nil {
+		t.Error("expected undefined variable error")
+	}
+}
+
+func TestContext_AssignClosestScope(t *testing.T) {
+	global := NewContext()
+	global.Set("a", &Int{Value: 1})
+	child := global.NewChild()
+	child.Set("b", &Int{Value: 1})
+
+	grandChild := child.NewChild()
+	if err := grandChild.Assign("a", &Int{Value: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := grandChild.Assign("b", &Int{Value: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := global.Get("a")
+	b, _ := child.Get("b")
+	if a.(*Int).Value != 2 || b.(*Int).Value != 3 {
+		t.Errorf("expected a = 2 and b = 3, got %s and %s", a, b)
+	}
+	if _, err := grandChild.Get("b"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestContext_AssignUndefined(t *testing.T) {
+	if err := NewContext().NewChild().Assign("nope", &Int{Value: 1}); err == nil {
+		t.Error("expected assignment to undefined variable to fail")
+	}
+}
@@ -0,0 +1,269 @@
+package object
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// Equal tells if two objects are structurally equal,
+// nil is only equal to itself
+func Equal(a, b Object) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(b)
+}
+
+// Hash returns a hash of an object, objects being Equal have equal hashes
+func Hash(o Object) uint64 {
+	if o == nil {
+		return 0
+	}
+	return o.Hash()
+}
+
+func hashString(t Type, s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte{byte(t)})
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func hashUint(t Type, v uint64) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 9)
+	buf[0] = byte(t)
+	binary.LittleEndian.PutUint64(buf[1:], v)
+	h.Write(buf)
+	return h.Sum64()
+}
+
+// hashElements hashes a sequence, the order of elements matters
+func hashElements(t Type, elements []Object) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	h.Write([]byte{byte(t)})
+	for _, el := range elements {
+		binary.LittleEndian.PutUint64(buf, Hash(el))
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+// hashIdentity hashes an object compared by identity
+func hashIdentity(t Type, o Object) uint64 {
+	return hashString(t, fmt.Sprintf("%p", o))
+}
+
+func equalElements(a, b []Object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal ...
+func (i Int) Equal(other Object) bool {
+	o, ok := other.(*Int)
+	return ok && i.Value == o.Value
+}
+
+// Hash ...
+func (i Int) Hash() uint64 {
+	return hashUint(TInt, uint64(i.Value))
+}
+
+// Equal ...
+func (f Float) Equal(other Object) bool {
+	o, ok := other.(*Float)
+	return ok && f.Value == o.Value
+}
+
+// Hash ...
+func (f Float) Hash() uint64 {
+	if f.Value == 0 {
+		// -0.0 is equal to 0.0
+		return hashUint(TFloat, 0)
+	}
+	return hashUint(TFloat, math.Float64bits(f.Value))
+}
+
+// Equal ...
+func (b BigInt) Equal(other Object) bool {
+	o, ok := other.(*BigInt)
+	return ok && b.Value.Cmp(o.Value) == 0
+}
+
+// Hash ...
+func (b BigInt) Hash() uint64 {
+	return hashString(TBigInt, b.Value.String())
+}
+
+// Equal ...
+func (r Ratio) Equal(other Object) bool {
+	o, ok := other.(*Ratio)
+	return ok && r.Value.Cmp(o.Value) == 0
+}
+
+// Hash ...
+func (r Ratio) Hash() uint64 {
+	return hashString(TRatio, r.Value.String())
+}
+
+// Equal ...
+func (s String) Equal(other Object) bool {
+	o, ok := other.(*String)
+	return ok && s.Value == o.Value
+}
+
+// Hash ...
+func (s String) Hash() uint64 {
+	return hashString(TString, s.Value)
+}
+
+// Equal ...
+func (r Rune) Equal(other Object) bool {
+	o, ok := other.(*Rune)
+	return ok && r.Value == o.Value
+}
+
+// Hash ...
+func (r Rune) Hash() uint64 {
+	return hashUint(TRune, uint64(r.Value))
+}
+
+// Equal ...
+func (b Bool) Equal(other Object) bool {
+	o, ok := other.(*Bool)
+	return ok && b.Value == o.Value
+}
+
+// Hash ...
+func (b Bool) Hash() uint64 {
+	if b.Value {
+		return hashUint(TBool, 1)
+	}
+	return hashUint(TBool, 0)
+}
+
+// Equal ...
+func (s Symbol) Equal(other Object) bool {
+	o, ok := other.(*Symbol)
+	return ok && s.Name == o.Name
+}
+
+// Hash ...
+func (s Symbol) Hash() uint64 {
+	return hashString(TSymbol, s.Name)
+}
+
+// Equal ...
+func (k Keyword) Equal(other Object) bool {
+	o, ok := other.(*Keyword)
+	return ok && k.Name == o.Name
+}
+
+// Hash ...
+func (k Keyword) Hash() uint64 {
+	return hashString(TKeyword, k.Name)
+}
+
+// Equal ...
+func (NilType) Equal(other Object) bool {
+	_, ok := other.(*NilType)
+	return ok
+}
+
+// Hash ...
+func (NilType) Hash() uint64 {
+	return hashUint(TNil, 0)
+}
+
+// Equal ...
+func (l List) Equal(other Object) bool {
+	o, ok := other.(*List)
+	return ok && equalElements(l.Elements, o.Elements)
+}
+
+// Hash ...
+func (l List) Hash() uint64 {
+	return hashElements(TList, l.Elements)
+}
+
+// Equal ...
+func (v Vector) Equal(other Object) bool {
+	o, ok := other.(*Vector)
+	return ok && equalElements(v.Elements, o.Elements)
+}
+
+// Hash ...
+func (v Vector) Hash() uint64 {
+	return hashElements(TVector, v.Elements)
+}
+
+// Equal tells if maps have equal values of equal keys
+func (m Map) Equal(other Object) bool {
+	o, ok := other.(*Map)
+	if !ok || m.Len() != o.Len() {
+		return false
+	}
+	for _, bucket := range m.buckets {
+		for _, pair := range bucket {
+			val, ok := o.Get(pair.Key)
+			if !ok || !Equal(pair.Value, val) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Hash doesn't depend on the order pairs are stored in
+func (m Map) Hash() uint64 {
+	var sum uint64
+	for _, bucket := range m.buckets {
+		for _, pair := range bucket {
+			sum += hashElements(TMap, []Object{pair.Key, pair.Value})
+		}
+	}
+	return hashUint(TMap, sum)
+}
+
+// Equal is true only for the very same function
+func (f *Function) Equal(other Object) bool {
+	return other == Object(f)
+}
+
+// Hash ...
+func (f *Function) Hash() uint64 {
+	return hashIdentity(TFunction, f)
+}
+
+// Equal is true for builtins of the same name, a builtin
+// value is made anew every time its name is looked up
+func (b *Builtin) Equal(other Object) bool {
+	o, ok := other.(*Builtin)
+	return ok && b.Name == o.Name
+}
+
+// Hash ...
+func (b *Builtin) Hash() uint64 {
+	return hashString(TBuiltin, b.Name)
+}
+
+// Equal is true only for the very same macro
+func (m *Macro) Equal(other Object) bool {
+	return other == Object(m)
+}
+
+// Hash ...
+func (m *Macro) Hash() uint64 {
+	return hashIdentity(TMacro, m)
+}
@@ -0,0 +1,146 @@
+package object
+
+import (
+	"sort"
+	"strings"
+)
+
+// MapPair is a key of a Map along with its value
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+// Map is a hash map, keys are looked up by Hash and compared
+// by Equal. Maps are values to glisp code: builtins changing
+// a map make a copy of it
+type Map struct {
+	buckets map[uint64][]MapPair
+	len     int
+}
+
+// NewMap constructs an empty Map
+func NewMap() *Map {
+	return &Map{buckets: make(map[uint64][]MapPair)}
+}
+
+// Get returns the value of a key, it's false if the key is missing
+func (m *Map) Get(key Object) (Object, bool) {
+	for _, pair := range m.buckets[Hash(key)] {
+		if Equal(pair.Key, key) {
+			return pair.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set puts a value under a key
+func (m *Map) Set(key, value Object) {
+	h := Hash(key)
+	bucket := m.buckets[h]
+	for i, pair := range bucket {
+		if Equal(pair.Key, key) {
+			bucket[i].Value = value
+			return
+		}
+	}
+	m.buckets[h] = append(bucket, MapPair{Key: key, Value: value})
+	m.len++
+}
+
+// Delete removes a key from the map
+func (m *Map) Delete(key Object) {
+	h := Hash(key)
+	bucket := m.buckets[h]
+	for i, pair := range bucket {
+		if !Equal(pair.Key, key) {
+			continue
+		}
+		if len(bucket) == 1 {
+			delete(m.buckets, h)
+		} else {
+			m.buckets[h] = append(bucket[:i:i], bucket[i+1:]...)
+		}
+		m.len--
+		return
+	}
+}
+
+// Len returns the number of keys
+func (m *Map) Len() int {
+	return m.len
+}
+
+// Copy makes a shallow copy of the map
+func (m *Map) Copy() *Map {
+	c := &Map{buckets: make(map[uint64][]MapPair, len(m.buckets)), len: m.len}
+	for h, bucket := range m.buckets {
+		c.buckets[h] = append([]MapPair(nil), bucket...)
+	}
+	return c
+}
+
+// Pairs returns pairs of the map ordered by keys: keys of the same
+// type are ordered by value and the ones of different types by type
+func (m *Map) Pairs() []MapPair {
+	pairs := make([]MapPair, 0, m.len)
+	for _, bucket := range m.buckets {
+		pairs = append(pairs, bucket...)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return keyLess(pairs[i].Key, pairs[j].Key)
+	})
+	return pairs
+}
+
+func keyLess(a, b Object) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	if ai, ok := a.(*Int); ok {
+		if bi, ok := b.(*Int); ok {
+			return ai.Value < bi.Value
+		}
+	}
+	// exact numbers are ordered by value whatever size they are
+	ar, aExact := exactRat(a)
+	br, bExact := exactRat(b)
+	if aExact && bExact {
+		return ar.Cmp(br) < 0
+	}
+	if aType, bType := keyType(a), keyType(b); aType != bType {
+		return aType < bType
+	}
+	switch av := a.(type) {
+	case *Float:
+		return av.Value < b.(*Float).Value
+	case *Rune:
+		return av.Value < b.(*Rune).Value
+	}
+	if as, bs := a.String(), b.String(); as != bs {
+		return as < bs
+	}
+	return a.Hash() < b.Hash()
+}
+
+// keyType is the type keys are grouped by, exact numbers are one group
+func keyType(o Object) Type {
+	if _, ok := exactRat(o); ok {
+		return TInt
+	}
+	return o.Type()
+}
+
+// String renders the map as {k v ...} ordered by keys
+func (m Map) String() string {
+	strPairs := make([]string, 0, m.len)
+	for _, pair := range m.Pairs() {
+		strPairs = append(strPairs, pair.Key.String()+" "+pair.Value.String())
+	}
+	return "{" + strings.Join(strPairs, " ") + "}"
+}
+
+// Type ...
+func (Map) Type() Type {
+	return TMap
+}
@@ -0,0 +1,68 @@
+package object
+
+import (
+	"math/big"
+)
+
+// BigInt is an integer not fitting in int64, integer
+// arithmetic overflows into it instead of wrapping around
+type BigInt struct {
+	Value *big.Int
+}
+
+// NewInteger makes an Int of v if it fits in int64 or a BigInt otherwise
+func NewInteger(v *big.Int) Object {
+	if v.IsInt64() {
+		return &Int{Value: v.Int64()}
+	}
+	return &BigInt{Value: v}
+}
+
+// String ...
+func (b BigInt) String() string {
+	return b.Value.String()
+}
+
+// Type ...
+func (BigInt) Type() Type {
+	return TBigInt
+}
+
+// Ratio is an exact fraction, it's always in lowest
+// terms and its denominator is greater than 1
+type Ratio struct {
+	Value *big.Rat
+}
+
+// NewRational makes a Ratio of v or an integer if v is a whole number
+func NewRational(v *big.Rat) Object {
+	if v.IsInt() {
+		return NewInteger(new(big.Int).Set(v.Num()))
+	}
+	return &Ratio{Value: v}
+}
+
+// String renders the ratio as n/d
+func (r Ratio) String() string {
+	return r.Value.String()
+}
+
+// Type ...
+func (Ratio) Type() Type {
+	return TRatio
+}
+
+// exactRat converts an integer or a ratio to *big.Rat,
+// it's false for any other object
+func exactRat(o Object) (*big.Rat, bool) {
+	switch v := o.(type) {
+	case *Int:
+		return new(big.Rat).SetInt64(v.Value), true
+	case *BigInt:
+		return new(big.Rat).SetInt(v.Value), true
+	case *Ratio:
+		return v.Value, true
+	default:
+		return nil, false
+	}
+}
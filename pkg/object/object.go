@@ -3,12 +3,15 @@ package object
 import (
 	"fmt"
 	"strings"
+	"sync"
+
+	"github.com/pmukhin/glisp/pkg/ast"
 )
 
 type Type int8
 
 const (
-	TInt      Type = iota
+	TInt Type = iota
 	TFunction
 	TString
 	TRune
@@ -16,6 +19,14 @@ const (
 	TBool
 	TList
 	TVector
+	TBuiltin
+	TSymbol
+	TMacro
+	TKeyword
+	TMap
+	TNil
+	TBigInt
+	TRatio
 )
 
 var type2str = map[Type]string{
@@ -27,6 +38,14 @@ var type2str = map[Type]string{
 	TBool:     "TBool",
 	TList:     "TList",
 	TVector:   "TVector",
+	TBuiltin:  "TBuiltin",
+	TSymbol:   "TSymbol",
+	TMacro:    "TMacro",
+	TKeyword:  "TKeyword",
+	TMap:      "TMap",
+	TNil:      "TNil",
+	TBigInt:   "TBigInt",
+	TRatio:    "TRatio",
 }
 
 func (t Type) String() string {
@@ -36,6 +55,10 @@ func (t Type) String() string {
 type Object interface {
 	String() string
 	Type() Type
+	// Equal tells if the object is structurally equal to other one
+	Equal(other Object) bool
+	// Hash is equal for objects being Equal
+	Hash() uint64
 }
 
 type Float struct {
@@ -106,18 +129,58 @@ func (b Bool) Type() Type {
 	return TBool
 }
 
+// NilType is the type of Nil
+type NilType struct{}
+
+// Nil is the only value of NilType, it's what expressions
+// having no meaningful value yield
+var Nil = &NilType{}
+
+// String ...
+func (NilType) String() string {
+	return "nil"
+}
+
+// Type ...
+func (NilType) Type() Type {
+	return TNil
+}
+
 // List ...
 type List struct {
 	Elements []Object
 }
 
-// String ...
+// String renders the list quoted, only the outermost list
+// gets the quote since whatever is inside of it is data already
 func (l List) String() string {
-	strElements := make([]string, len(l.Elements))
-	for i, el := range l.Elements {
-		strElements[i] = el.String()
+	return "'" + datumString(&l)
+}
+
+// datumString renders an object standing inside of a quoted list
+func datumString(o Object) string {
+	switch v := o.(type) {
+	case *List:
+		return "(" + joinDatums(v.Elements) + ")"
+	case *Vector:
+		return "[" + joinDatums(v.Elements) + "]"
+	case *Map:
+		elements := make([]Object, 0, 2*v.Len())
+		for _, pair := range v.Pairs() {
+			elements = append(elements, pair.Key, pair.Value)
+		}
+		return "{" + joinDatums(elements) + "}"
+	default:
+		return o.String()
+	}
+}
+
+func joinDatums(elements []Object) string {
+	strElements := make([]string, len(elements))
+	for i, el := range elements {
+		strElements[i] = datumString(el)
 	}
-	return "'(" + strings.Join(strElements, " ") + ")"
+	return strings.Join(strElements, " ")
 }
 
 // Type ...
@@ -143,3 +206,112 @@ func (v Vector) String() string {
 func (Vector) Type() Type {
 	return TVector
 }
+
+// Function is a user-defined function closing over
+// the context it has been defined in, if Rest is not empty
+// args left after Params are bound to it as a list
+type Function struct {
+	Name   string
+	Params []string
+	Rest   string
+	Body   []ast.Expression
+	Env    Context
+}
+
+// String ...
+func (f Function) String() string {
+	return fmt.Sprintf("<function %s>", f.Name)
+}
+
+// Type ...
+func (Function) Type() Type {
+	return TFunction
+}
+
+// BuiltinFunction is a function implemented by the interpreter itself
+type BuiltinFunction func(args ...Object) (Object, error)
+
+// Builtin is an internal function used as a value
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+// String ...
+func (b Builtin) String() string {
+	return fmt.Sprintf("<builtin %s>", b.Name)
+}
+
+// Type ...
+func (Builtin) Type() Type {
+	return TBuiltin
+}
+
+// Symbol is a name taken as data
+type Symbol struct {
+	Name string
+}
+
+// String ...
+func (s Symbol) String() string {
+	return s.Name
+}
+
+// Type ...
+func (Symbol) Type() Type {
+	return TSymbol
+}
+
+// Macro is a user-defined macro, it's called with unevaluated
+// forms as args and returns a form to be evaluated instead of it
+type Macro struct {
+	Name   string
+	Params []string
+	Rest   string
+	Body   []ast.Expression
+	Env    Context
+}
+
+// String ...
+func (m Macro) String() string {
+	return fmt.Sprintf("<macro %s>", m.Name)
+}
+
+// Type ...
+func (Macro) Type() Type {
+	return TMacro
+}
+
+// Keyword is a self-evaluating name, keywords are interned:
+// there's a single *Keyword for a name so they're compared by pointer
+type Keyword struct {
+	Name string
+}
+
+var keywords = struct {
+	sync.Mutex
+	table map[string]*Keyword
+}{table: make(map[string]*Keyword)}
+
+// NewKeyword returns the keyword of a name creating it on first use
+func NewKeyword(name string) *Keyword {
+	keywords.Lock()
+	defer keywords.Unlock()
+
+	if k, ok := keywords.table[name]; ok {
+		return k
+	}
+	k := &Keyword{Name: name}
+	keywords.table[name] = k
+	return k
+}
+
+// String ...
+func (k Keyword) String() string {
+	return ":" + k.Name
+}
+
+// Type ...
+func (Keyword) Type() Type {
+	return TKeyword
+}
@@ -0,0 +1,110 @@
+package object
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestNewKeyword_IsInterned(t *testing.T) {
+	if NewKeyword("a") != NewKeyword("a") {
+		t.Error("expected keywords of the same name to be the same object")
+	}
+	if NewKeyword("a") == NewKeyword("b") {
+		t.Error("expected keywords of different names to differ")
+	}
+	if s := NewKeyword("a").String(); s != ":a" {
+		t.Errorf("expected :a, got %s", s)
+	}
+}
+
+func TestMap(t *testing.T) {
+	m := NewMap()
+	m.Set(&Int{Value: 10}, &String{Value: "ten"})
+	m.Set(&Int{Value: 2}, &String{Value: "two"})
+	m.Set(NewKeyword("k"), &Int{Value: 1})
+	m.Set(&String{Value: "s"}, &Int{Value: 2})
+
+	m.Set(&List{Elements: []Object{&Int{Value: 1}}}, &Int{Value: 3})
+	if v, ok := m.Get(&List{Elements: []Object{&Int{Value: 1}}}); !ok || v.String() != "3" {
+		t.Errorf("expected an equal list to be the same key, got %v", v)
+	}
+	m.Delete(&List{Elements: []Object{&Int{Value: 1}}})
+	if v, ok := m.Get(&Int{Value: 2}); !ok || v.String() != "two" {
+		t.Errorf("expected two, got %v", v)
+	}
+	if _, ok := m.Get(&Float{Value: 2}); ok {
+		t.Error("expected 2.0 not to be the same key as 2")
+	}
+
+	c := m.Copy()
+	c.Delete(&Int{Value: 10})
+	if m.Len() != 4 || c.Len() != 3 {
+		t.Errorf("expected the copy only to change, got %d and %d keys", m.Len(), c.Len())
+	}
+
+	if s := m.String(); s != "{2 two 10 ten s 2 :k 1}" {
+		t.Errorf("unexpected rendering %s", s)
+	}
+}
+
+func TestMap_ExactNumberKeys(t *testing.T) {
+	big2p64, _ := new(big.Int).SetString("18446744073709551616", 10)
+	m := NewMap()
+	m.Set(&Int{Value: 3}, &Int{Value: 3})
+	m.Set(NewInteger(big2p64), &Int{Value: 4})
+	m.Set(NewRational(big.NewRat(1, 2)), &Int{Value: 1})
+	m.Set(&Int{Value: -1}, &Int{Value: 0})
+	m.Set(&String{Value: "s"}, &Int{Value: 5})
+
+	if s := m.String(); s != "{-1 0 1/2 1 3 3 18446744073709551616 4 s 5}" {
+		t.Errorf("expected exact numbers ordered by value, got %s", s)
+	}
+}
+
+func TestEqualAndHash(t *testing.T) {
+	list := func(els ...Object) Object { return &List{Elements: els} }
+	m1, m2 := NewMap(), NewMap()
+	m1.Set(NewKeyword("a"), &Int{Value: 1})
+	m1.Set(NewKeyword("b"), list(&String{Value: "x"}))
+	m2.Set(NewKeyword("b"), list(&String{Value: "x"}))
+	m2.Set(NewKeyword("a"), &Int{Value: 1})
+	fn := &Function{Name: "f"}
+
+	equal := [][2]Object{
+		{&Int{Value: 1}, &Int{Value: 1}},
+		{&Float{Value: 0}, &Float{Value: math.Copysign(0, -1)}},
+		{&String{Value: "a"}, &String{Value: "a"}},
+		{&Rune{Value: 'a'}, &Rune{Value: 'a'}},
+		{&Bool{Value: true}, &Bool{Value: true}},
+		{&Symbol{Name: "s"}, &Symbol{Name: "s"}},
+		{list(&Int{Value: 1}, list()), list(&Int{Value: 1}, list())},
+		{&Vector{Elements: []Object{&Int{Value: 1}}}, &Vector{Elements: []Object{&Int{Value: 1}}}},
+		{m1, m2},
+		{fn, fn},
+		{&Builtin{Name: "+"}, &Builtin{Name: "+"}},
+	}
+	for _, pair := range equal {
+		if !Equal(pair[0], pair[1]) {
+			t.Errorf("expected %s and %s to be equal", pair[0], pair[1])
+		}
+		if Hash(pair[0]) != Hash(pair[1]) {
+			t.Errorf("expected %s and %s to have equal hashes", pair[0], pair[1])
+		}
+	}
+
+	notEqual := [][2]Object{
+		{&Int{Value: 1}, &Float{Value: 1}},
+		{&String{Value: "a"}, &Symbol{Name: "a"}},
+		{list(&Int{Value: 1}), &Vector{Elements: []Object{&Int{Value: 1}}}},
+		{list(&Int{Value: 1}), list(&Int{Value: 1}, &Int{Value: 2})},
+		{m1, NewMap()},
+		{fn, &Function{Name: "f"}},
+		{&Int{Value: 1}, nil},
+	}
+	for _, pair := range notEqual {
+		if Equal(pair[0], pair[1]) {
+			t.Errorf("expected %v and %v to differ", pair[0], pair[1])
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/token"
+)
+
+// SetMacroLookup makes the parser treat calls of names isMacro
+// is true for as macro calls, it's needed for macros defined
+// before parsing has started, e.g. in previous lines of REPL
+func (p *Parser) SetMacroLookup(isMacro func(name string) bool) {
+	p.isMacro = isMacro
+}
+
+// parseDefmacro parses a macro definition and makes
+// the rest of the source treat calls of it as macro calls
+func (p *Parser) parseDefmacro(tok token.Token) ast.Expression {
+	de := &ast.DefmacroExpression{Token: tok}
+	de.Name = p.parseIdentifier().(*ast.IdentifierExpression)
+	de.Params, de.Rest = p.parseParamList()
+	de.Body = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	if _, ok := p.tok2macro[de.Name.Value]; ok {
+		p.expectError("macro %s is already defined", de.Name.Value)
+		return de
+	}
+	p.tok2macro[de.Name.Value] = p.parseMacroCall
+
+	return de
+}
+
+// parseMacroCall parses args of a macro as data
+func (p *Parser) parseMacroCall(tok token.Token) ast.Expression {
+	mce := &ast.MacroCallExpression{Token: tok}
+	mce.Name = &ast.IdentifierExpression{Token: tok, Value: tok.Literal}
+	mce.Args = p.parseDatumList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return mce
+}
+
+func (p *Parser) parseQuasiQuote() ast.Expression {
+	if p.inQuasiQuote {
+		p.expectError("nested quasiquote is not supported")
+		return nil
+	}
+
+	qqe := &ast.QuasiQuoteExpression{Token: p.currToken}
+	p.next() // eat "`"
+
+	p.inQuasiQuote = true
+	qqe.Value = p.parseDatum()
+	p.inQuasiQuote = false
+
+	return qqe
+}
+
+// parseDatum parses a form as data: identifiers are symbols and
+// lists are not function calls, inside of a quasiquote `,` and `,@`
+// mark expressions to be evaluated
+func (p *Parser) parseDatum() ast.Expression {
+	switch p.currToken.Type {
+	case token.ParenOp:
+		le := &ast.ListExpression{Token: p.currToken}
+		p.next() // eat `(`
+		le.Elements = p.parseDatumList()
+
+		p.assert(token.ParenCl)
+		p.next() // eat `)`
+
+		return le
+	case token.BracketOp:
+		ve := &ast.VectorExpression{Token: p.currToken}
+		p.next() // eat `[`
+		ve.Elements = p.parseDatumList()
+
+		p.assert(token.BracketCl)
+		p.next() // eat `]`
+
+		return ve
+	case token.BraceOp:
+		me := &ast.MapExpression{Token: p.currToken}
+		p.next() // eat `{`
+		me.Keys, me.Values = p.splitPairs(p.parseDatumList())
+
+		p.assert(token.BraceCl)
+		p.next() // eat `}`
+
+		return me
+	case token.SingleQuote:
+		// 'x is read as (quote x)
+		quoteToken := p.currToken
+		p.next() // eat `'`
+		quote := &ast.IdentifierExpression{Token: quoteToken, Value: "quote"}
+
+		return &ast.ListExpression{
+			Token:    quoteToken,
+			Elements: []ast.Expression{quote, p.parseDatum()},
+		}
+	case token.Comma, token.CommaAt:
+		if !p.inQuasiQuote {
+			p.expectError("%s outside of quasiquote", p.currToken.Type)
+			return nil
+		}
+		tok := p.currToken
+		p.next() // eat `,` or `,@`
+
+		// the unquoted part is code again
+		p.inQuasiQuote = false
+		value := p.parseExpression()
+		p.inQuasiQuote = true
+
+		if tok.Type == token.CommaAt {
+			return &ast.UnquoteSplicingExpression{Token: tok, Value: value}
+		}
+		return &ast.UnquoteExpression{Token: tok, Value: value}
+	default:
+		return p.parseExpression()
+	}
+}
+
+func (p *Parser) parseDatumList() []ast.Expression {
+	ls := make([]ast.Expression, 0, 8)
+	for p.currToken.Type != token.ParenCl &&
+		p.currToken.Type != token.BracketCl &&
+		p.currToken.Type != token.BraceCl &&
+		p.currToken.Type != token.EOF {
+		res := p.parseDatum()
+		if res == nil || p.failed {
+			return nil
+		}
+		ls = append(ls, res)
+	}
+
+	return ls
+}
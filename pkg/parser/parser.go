@@ -1,46 +1,85 @@
 package parser
 
 import (
-	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/pmukhin/glisp/pkg/ast"
-	"github.com/pmukhin/glisp/pkg/scanner"
+	"github.com/pmukhin/glisp/pkg/diag"
 	"github.com/pmukhin/glisp/pkg/token"
 )
 
+// TokenSource produces tokens one by one ending with token.EOF,
+// scanner.Scanner is the one reading source code
+type TokenSource interface {
+	Next() token.Token
+}
+
 type Parser struct {
 	tokBackup []token.Token
 
 	tok2infix map[token.Type]func() ast.Expression
 	tok2macro map[string]func(token.Token) ast.Expression
 
-	scn       *scanner.Scanner
+	// isMacro tells about macros defined outside of parsed source
+	isMacro func(name string) bool
+	// inQuasiQuote is set while parsing a template of a quasiquote
+	inQuasiQuote bool
+
+	scn       TokenSource
 	currToken token.Token
-	error     error
+	// errors holds syntax errors of all the top-level forms parsed so far
+	errors diag.List
+	// failed is set once an error is found in the current top-level form,
+	// further errors of the form are most likely caused by the first one
+	failed bool
+	// depth is the nesting of parens and brackets consumed so far
+	depth int
 }
 
-func New(scn *scanner.Scanner) *Parser {
+func New(scn TokenSource) *Parser {
 	p := new(Parser)
 	// init backup
 	p.tokBackup = make([]token.Token, 0, 256)
 
 	p.scn = scn
-	p.error = nil
-	p.currToken = token.Token{Type: token.EOF, Literal: "EOF", Pos: -1}
+	p.currToken = token.Token{Type: token.EOF, Literal: "EOF"}
 
 	p.tok2infix = make(map[token.Type]func() ast.Expression)
 	p.tok2infix[token.ParenOp] = p.parseFunctionCall
-	p.tok2infix[token.SingleQuote] = p.parseList
+	p.tok2infix[token.SingleQuote] = p.parseQuote
 	p.tok2infix[token.Integer] = p.parseInteger
 	p.tok2infix[token.Float] = p.parseFloat
 	p.tok2infix[token.String] = p.parseString
-	//p.tok2infix[token.Rune] = p.parseRune
-	p.tok2infix[token.Identifier] = p.parseIdentifier
+	p.tok2infix[token.Rune] = p.parseRune
+	p.tok2infix[token.Keyword] = p.parseKeyword
+	p.tok2infix[token.Identifier] = p.parseName
 	p.tok2infix[token.BracketOp] = p.parseVector
+	p.tok2infix[token.BraceOp] = p.parseMap
+	p.tok2infix[token.Backquote] = p.parseQuasiQuote
+	p.tok2infix[token.Illegal] = p.parseIllegal
 
 	p.tok2macro = make(map[string]func(token.Token) ast.Expression)
 	p.tok2macro["defvar"] = p.parseDefVar
+	p.tok2macro["defun"] = p.parseDefun
+	p.tok2macro["lambda"] = p.parseLambda
+	p.tok2macro["if"] = p.parseIf
+	p.tok2macro["cond"] = p.parseCond
+	p.tok2macro["when"] = p.parseWhen
+	p.tok2macro["unless"] = p.parseUnless
+	p.tok2macro["and"] = p.parseAnd
+	p.tok2macro["or"] = p.parseOr
+	p.tok2macro["let"] = p.parseLet
+	p.tok2macro["let*"] = p.parseLet
+	p.tok2macro["setq"] = p.parseSetq
+	p.tok2macro["set!"] = p.parseSetq
+	p.tok2macro["while"] = p.parseWhile
+	p.tok2macro["dotimes"] = p.parseDotimes
+	p.tok2macro["dolist"] = p.parseDolist
+	p.tok2macro["defmacro"] = p.parseDefmacro
+	p.tok2macro["quote"] = p.parseQuoteForm
 
 	p.next()
 
@@ -48,14 +87,43 @@ func New(scn *scanner.Scanner) *Parser {
 }
 
 func (p *Parser) next() {
+	switch p.currToken.Type {
+	case token.ParenOp, token.BracketOp, token.BraceOp:
+		p.depth++
+	case token.ParenCl, token.BracketCl, token.BraceCl:
+		if p.depth > 0 {
+			p.depth--
+		}
+	}
 	p.tokBackup = append(p.tokBackup, p.currToken)
 
 	tok := p.scn.Next()
 	p.currToken = tok
 }
 
+// expectError records an error at the position of the current token,
+// only the first error of a top-level form is recorded
 func (p *Parser) expectError(msg string, a ...interface{}) {
-	p.error = fmt.Errorf(msg, a...)
+	if p.failed {
+		return
+	}
+	p.failed = true
+	p.errors = append(p.errors, diag.At(p.currToken.Pos, diag.Syntax, msg, a...))
+}
+
+// synchronize skips the rest of a broken top-level form
+// so that parsing goes on from the next one, start is the
+// number of tokens consumed before the broken form
+func (p *Parser) synchronize(start int) {
+	for p.depth > 0 && p.currToken.Type != token.EOF {
+		p.next()
+	}
+	// a form nothing could be parsed from, e.g. a stray `)`
+	if len(p.tokBackup) == start && p.currToken.Type != token.EOF {
+		p.next()
+	}
+	p.depth = 0
+	p.failed = false
 }
 
 func (p *Parser) assert(typ token.Type) {
@@ -66,10 +134,30 @@ func (p *Parser) assert(typ token.Type) {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
-	p.assert(token.Identifier)
-	defer p.next() // eat Identifier
+	ie := &ast.IdentifierExpression{Token: p.currToken, Value: p.currToken.Literal}
+	if p.currToken.Type != token.Identifier {
+		// leave the token to the caller, it may well be the end of the form
+		p.assert(token.Identifier)
+		return ie
+	}
+	p.next() // eat Identifier
+
+	return ie
+}
 
-	return &ast.IdentifierExpression{Token: p.currToken, Value: p.currToken.Literal}
+// parseName parses an identifier in place of an expression
+// where nil, true and false are literals rather than names
+func (p *Parser) parseName() ast.Expression {
+	tok := p.currToken
+	switch tok.Literal {
+	case "nil":
+		p.next() // eat `nil`
+		return &ast.NilExpression{Token: tok}
+	case "true", "false":
+		p.next() // eat `true` or `false`
+		return &ast.BoolExpression{Token: tok, Value: tok.Literal == "true"}
+	}
+	return p.parseIdentifier()
 }
 
 func (p *Parser) parseStatement() ast.Statement {
@@ -98,6 +186,34 @@ func (p *Parser) parseExpression() ast.Expression {
 	return infixParse()
 }
 
+// parseIllegal reports a token the scanner couldn't make sense of,
+// its literal is the offending piece of source
+func (p *Parser) parseIllegal() ast.Expression {
+	lit := p.currToken.Literal
+	switch {
+	case lit == `"`:
+		p.expectError("unterminated string")
+	case lit == `"""`:
+		p.expectError("unterminated raw string")
+	case strings.HasPrefix(lit, `\`):
+		p.expectError("invalid escape sequence %s", lit)
+	case lit == "#|":
+		p.expectError("unterminated block comment")
+	case lit == "#;":
+		p.expectError("datum comment with no datum")
+	case strings.HasPrefix(lit, `#\`):
+		p.expectError("invalid character literal %s", lit)
+	case strings.IndexAny(lit, "0123456789") == 0,
+		len(lit) > 1 && strings.IndexAny(lit, "+-") == 0:
+		p.expectError("malformed number %s", lit)
+	default:
+		p.expectError("illegal character %s", lit)
+	}
+	p.next() // eat Illegal
+
+	return nil
+}
+
 func (p *Parser) parseDefVar(tok token.Token) ast.Expression {
 	dve := &ast.DefVarExpression{Token: tok}
 	dve.Name = p.parseIdentifier().(*ast.IdentifierExpression)
@@ -114,19 +230,272 @@ func (p *Parser) parseDefVar(tok token.Token) ast.Expression {
 	return dve
 }
 
-func (p *Parser) parseList() ast.Expression {
-	le := &ast.ListExpression{Token: p.currToken}
-	p.next() // eat `'`
+func (p *Parser) parseDefun(tok token.Token) ast.Expression {
+	de := &ast.DefunExpression{Token: tok}
+	de.Name = p.parseIdentifier().(*ast.IdentifierExpression)
+	de.Params, de.Rest = p.parseParamList()
+
+	// have comment? a string being the only form is the body itself
+	if p.currToken.Type == token.String {
+		str := p.parseString()
+		if p.currToken.Type == token.ParenCl {
+			de.Body = append(de.Body, str)
+		} else {
+			de.Comment = str
+		}
+	}
+	de.Body = append(de.Body, p.parseExpressionList()...)
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return de
+}
+
+func (p *Parser) parseLambda(tok token.Token) ast.Expression {
+	le := &ast.LambdaExpression{Token: tok}
+	le.Params, le.Rest = p.parseParamList()
+	le.Body = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return le
+}
+
+func (p *Parser) parseIf(tok token.Token) ast.Expression {
+	ie := &ast.IfExpression{Token: tok}
+	ie.Condition = p.parseExpression()
+	ie.Consequence = p.parseExpression()
+
+	// have else branch?
+	if p.currToken.Type != token.ParenCl {
+		ie.Alternative = p.parseExpression()
+	}
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return ie
+}
+
+func (p *Parser) parseCond(tok token.Token) ast.Expression {
+	ce := &ast.CondExpression{Token: tok}
+	ce.Clauses = make([]*ast.CondClause, 0, 4)
+
+	for p.currToken.Type == token.ParenOp {
+		p.next() // eat `(`
+
+		clause := &ast.CondClause{}
+		if p.currToken.Type == token.Identifier && p.currToken.Literal == "else" {
+			p.next() // eat `else`
+		} else {
+			clause.Condition = p.parseExpression()
+		}
+		clause.Body = p.parseExpressionList()
+
+		p.assert(token.ParenCl)
+		p.next() // eat `)`
+
+		ce.Clauses = append(ce.Clauses, clause)
+	}
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return ce
+}
+
+func (p *Parser) parseWhen(tok token.Token) ast.Expression {
+	we := &ast.WhenExpression{Token: tok}
+	we.Condition = p.parseExpression()
+	we.Body = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return we
+}
+
+func (p *Parser) parseUnless(tok token.Token) ast.Expression {
+	ue := &ast.UnlessExpression{Token: tok}
+	ue.Condition = p.parseExpression()
+	ue.Body = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return ue
+}
+
+func (p *Parser) parseAnd(tok token.Token) ast.Expression {
+	ae := &ast.AndExpression{Token: tok}
+	ae.Args = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return ae
+}
+
+func (p *Parser) parseOr(tok token.Token) ast.Expression {
+	oe := &ast.OrExpression{Token: tok}
+	oe.Args = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return oe
+}
+
+func (p *Parser) parseLet(tok token.Token) ast.Expression {
+	le := &ast.LetExpression{Token: tok, Sequential: tok.Literal == "let*"}
+	le.Bindings = make([]*ast.LetBinding, 0, 4)
 
 	p.assert(token.ParenOp)
 	p.next() // eat `(`
 
-	le.Elements = p.parseExpressionList()
+	for p.currToken.Type == token.ParenOp {
+		p.next() // eat `(`
+
+		binding := &ast.LetBinding{}
+		binding.Name = p.parseIdentifier().(*ast.IdentifierExpression)
+		binding.Value = p.parseExpression()
+
+		p.assert(token.ParenCl)
+		p.next() // eat `)`
+
+		le.Bindings = append(le.Bindings, binding)
+	}
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	le.Body = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
 	p.next() // eat `)`
 
 	return le
 }
 
+func (p *Parser) parseSetq(tok token.Token) ast.Expression {
+	se := &ast.SetqExpression{Token: tok}
+	se.Names = make([]*ast.IdentifierExpression, 0, 2)
+	se.Values = make([]ast.Expression, 0, 2)
+
+	for p.currToken.Type != token.ParenCl && p.currToken.Type != token.EOF {
+		se.Names = append(se.Names, p.parseIdentifier().(*ast.IdentifierExpression))
+		se.Values = append(se.Values, p.parseExpression())
+		if p.failed {
+			return se
+		}
+	}
+
+	if len(se.Names) == 0 {
+		p.expectError("%s expects at least one name and value", tok.Literal)
+	}
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return se
+}
+
+func (p *Parser) parseWhile(tok token.Token) ast.Expression {
+	we := &ast.WhileExpression{Token: tok}
+	we.Condition = p.parseExpression()
+	we.Body = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return we
+}
+
+func (p *Parser) parseDotimes(tok token.Token) ast.Expression {
+	de := &ast.DotimesExpression{Token: tok}
+	de.Var, de.Count, de.Result = p.parseLoopSpec()
+	de.Body = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return de
+}
+
+func (p *Parser) parseDolist(tok token.Token) ast.Expression {
+	de := &ast.DolistExpression{Token: tok}
+	de.Var, de.List, de.Result = p.parseLoopSpec()
+	de.Body = p.parseExpressionList()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return de
+}
+
+// parseLoopSpec parses `(var value result)` where result is optional
+func (p *Parser) parseLoopSpec() (*ast.IdentifierExpression, ast.Expression, ast.Expression) {
+	p.assert(token.ParenOp)
+	p.next() // eat `(`
+
+	v := p.parseIdentifier().(*ast.IdentifierExpression)
+	value := p.parseExpression()
+
+	var result ast.Expression
+	if p.currToken.Type != token.ParenCl {
+		result = p.parseExpression()
+	}
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return v, value, result
+}
+
+// parseParamList parses `(a b &rest c)` where &rest part is optional
+func (p *Parser) parseParamList() ([]*ast.IdentifierExpression, *ast.IdentifierExpression) {
+	p.assert(token.ParenOp)
+	p.next() // eat `(`
+
+	params := make([]*ast.IdentifierExpression, 0, 4)
+	var rest *ast.IdentifierExpression
+	for p.currToken.Type == token.Identifier {
+		if p.currToken.Literal == "&rest" {
+			p.next() // eat `&rest`
+			rest = p.parseIdentifier().(*ast.IdentifierExpression)
+			break
+		}
+		params = append(params, p.parseIdentifier().(*ast.IdentifierExpression))
+	}
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return params, rest
+}
+
+// parseQuote parses 'datum
+func (p *Parser) parseQuote() ast.Expression {
+	qe := &ast.QuoteExpression{Token: p.currToken}
+	p.next() // eat `'`
+	qe.Value = p.parseDatum()
+
+	return qe
+}
+
+// parseQuoteForm parses (quote datum)
+func (p *Parser) parseQuoteForm(tok token.Token) ast.Expression {
+	qe := &ast.QuoteExpression{Token: tok}
+	qe.Value = p.parseDatum()
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
+
+	return qe
+}
+
 func (p *Parser) parseVector() ast.Expression {
 	ve := &ast.VectorExpression{Token: p.currToken}
 	p.next() // eat `[`
@@ -139,12 +508,41 @@ func (p *Parser) parseVector() ast.Expression {
 	return ve
 }
 
+func (p *Parser) parseMap() ast.Expression {
+	me := &ast.MapExpression{Token: p.currToken}
+	p.next() // eat `{`
+
+	me.Keys, me.Values = p.splitPairs(p.parseExpressionList())
+
+	p.assert(token.BraceCl)
+	p.next() // eat `}`
+
+	return me
+}
+
+// splitPairs splits elements of a map literal into keys and values
+func (p *Parser) splitPairs(elements []ast.Expression) ([]ast.Expression, []ast.Expression) {
+	if len(elements)%2 != 0 {
+		p.expectError("map literal expects an even number of forms, %d given", len(elements))
+		return nil, nil
+	}
+
+	keys := make([]ast.Expression, 0, len(elements)/2)
+	values := make([]ast.Expression, 0, len(elements)/2)
+	for i := 0; i < len(elements); i += 2 {
+		keys = append(keys, elements[i])
+		values = append(values, elements[i+1])
+	}
+	return keys, values
+}
+
 func (p *Parser) parseExpressionList() []ast.Expression {
 	ls := make([]ast.Expression, 0, 8)
 	for p.currToken.Type != token.ParenCl &&
-		p.currToken.Type != token.BracketCl {
+		p.currToken.Type != token.BracketCl &&
+		p.currToken.Type != token.BraceCl {
 		res := p.parseExpression()
-		if res == nil || p.error != nil {
+		if res == nil || p.failed {
 			return nil
 		}
 		ls = append(ls, res)
@@ -157,28 +555,54 @@ func (p *Parser) parseFunctionCall() ast.Expression {
 	prToken := p.currToken // if it's a fun call
 	p.next()               // eat `(`
 
-	idToken := p.currToken // if it's a macro
-	callee := p.parseIdentifier()
-	macroFun, ok := p.tok2macro[callee.(*ast.IdentifierExpression).Value]
-	if ok {
-		return macroFun(idToken)
+	if p.currToken.Type == token.Identifier {
+		macroFun, ok := p.tok2macro[p.currToken.Literal]
+		if !ok && p.isMacro != nil && p.isMacro(p.currToken.Literal) {
+			macroFun, ok = p.parseMacroCall, true
+		}
+		if ok {
+			idToken := p.currToken
+			p.next() // eat macro name
+			return macroFun(idToken)
+		}
 	}
 
 	fc := &ast.FunctionCall{Token: prToken}
 
-	fc.Callee = callee
+	fc.Callee = p.parseExpression()
 	fc.Args = p.parseExpressionList()
-	p.next() // eat ')'
+
+	p.assert(token.ParenCl)
+	p.next() // eat `)`
 
 	return fc
 }
 
+// numberLiteral strips digit separators of a literal and
+// tells the base it's written in, 0 stands for a prefixed one
+func numberLiteral(lit string) (string, int) {
+	clean := strings.Replace(lit, "_", "", -1)
+	unsigned := strings.TrimLeft(clean, "+-")
+	if len(unsigned) > 1 && unsigned[0] == '0' && strings.ContainsRune("xXoObB", rune(unsigned[1])) {
+		return clean, 0
+	}
+	return clean, 10
+}
+
 func (p *Parser) parseInteger() ast.Expression {
 	ie := &ast.IntegerExpression{Token: p.currToken}
-	v, err := strconv.ParseInt(p.currToken.Literal, 10, 64)
+	lit, base := numberLiteral(p.currToken.Literal)
+	v, err := strconv.ParseInt(lit, base, 64)
 
+	if err != nil && err.(*strconv.NumError).Err == strconv.ErrRange {
+		if bv, ok := new(big.Int).SetString(lit, base); ok {
+			p.next() // eat Integer
+			return &ast.BigIntegerExpression{Token: ie.Token, Value: bv}
+		}
+	}
 	if err != nil {
-		p.expectError(err.Error())
+		p.expectError("malformed integer literal %s", p.currToken.Literal)
+		p.next() // eat Integer
 		return nil
 	}
 
@@ -190,18 +614,40 @@ func (p *Parser) parseInteger() ast.Expression {
 
 func (p *Parser) parseFloat() ast.Expression {
 	fe := &ast.FloatExpression{Token: p.currToken}
-	v, err := strconv.ParseFloat(p.currToken.Literal, 64)
+	lit, _ := numberLiteral(p.currToken.Literal)
+	v, err := strconv.ParseFloat(lit, 64)
 
 	if err != nil {
-		p.expectError(err.Error())
+		if err.(*strconv.NumError).Err == strconv.ErrRange {
+			p.expectError("float literal %s overflows float64", p.currToken.Literal)
+		} else {
+			p.expectError("malformed float literal %s", p.currToken.Literal)
+		}
+		p.next() // eat Float
+		return nil
 	}
 
 	fe.Value = v
-	p.next()
+	p.next() // eat Float
 
 	return fe
 }
 
+func (p *Parser) parseRune() ast.Expression {
+	re := &ast.RuneExpression{Token: p.currToken}
+	re.Value, _ = utf8.DecodeRuneInString(p.currToken.Literal)
+	p.next() // eat Rune
+
+	return re
+}
+
+func (p *Parser) parseKeyword() ast.Expression {
+	ke := &ast.KeywordExpression{Token: p.currToken, Value: p.currToken.Literal}
+	p.next() // eat Keyword
+
+	return ke
+}
+
 func (p *Parser) parseString() ast.Expression {
 	se := &ast.StringExpression{Token: p.currToken}
 	se.Value = p.currToken.Literal
@@ -210,23 +656,31 @@ func (p *Parser) parseString() ast.Expression {
 	return se
 }
 
+// Parse parses all the top-level forms, a form having a syntax error
+// is skipped and parsing goes on from the next one. The program of
+// the forms parsed successfully is returned along with diag.List
+// of all the errors found
 func (p *Parser) Parse() (*ast.Program, error) {
 	program := new(ast.Program)
 	statements := make([]ast.Statement, 0, 256)
 
 	for {
+		start := len(p.tokBackup)
 		stmt := p.parseStatement()
 		if stmt == nil {
 			break
 		}
+		if p.failed {
+			p.synchronize(start)
+			continue
+		}
 		statements = append(statements, stmt)
 	}
-
-	if p.error != nil {
-		return nil, p.error
-	}
 	program.Statements = statements
 
+	if len(p.errors) > 0 {
+		return program, p.errors
+	}
 	return program, nil
 }
 
@@ -4,12 +4,18 @@ import (
 	"reflect"
 	"testing"
 
+	"fmt"
 	"github.com/pmukhin/glisp/pkg/ast"
+	"github.com/pmukhin/glisp/pkg/diag"
 	"github.com/pmukhin/glisp/pkg/scanner"
 	"github.com/pmukhin/glisp/pkg/token"
-	"fmt"
 )
 
+// pos makes a position of a given offset in a single line source
+func pos(offset int) token.Position {
+	return token.Position{Offset: offset, Line: 1, Column: offset + 1}
+}
+
 // do does the testwork
 func do(t *testing.T, s string, e []ast.Statement) {
 	scn := scanner.New(s)
@@ -33,21 +39,24 @@ func TestParser_Parse_MacroDefVar_WithOutComment(t *testing.T) {
 	do(t, `(defvar int-list '(1 2))`, []ast.Statement{
 		&ast.ExpressionStatement{
 			Expression: &ast.DefVarExpression{
-				Token: token.New(token.Identifier, 1, "defvar"),
+				Token: token.New(token.Identifier, pos(1), "defvar"),
 				Name: &ast.IdentifierExpression{
-					Token: token.New(token.Identifier, 8, "int-list"),
+					Token: token.New(token.Identifier, pos(8), "int-list"),
 					Value: "int-list",
 				},
-				Value: &ast.ListExpression{
-					Token: token.New(token.SingleQuote, 17),
-					Elements: []ast.Expression{
-						&ast.IntegerExpression{
-							Token: token.New(token.Integer, 19, "1"),
-							Value: 1,
-						},
-						&ast.IntegerExpression{
-							Token: token.New(token.Integer, 21, "2"),
-							Value: 2,
+				Value: &ast.QuoteExpression{
+					Token: token.New(token.SingleQuote, pos(17)),
+					Value: &ast.ListExpression{
+						Token: token.New(token.ParenOp, pos(18)),
+						Elements: []ast.Expression{
+							&ast.IntegerExpression{
+								Token: token.New(token.Integer, pos(19), "1"),
+								Value: 1,
+							},
+							&ast.IntegerExpression{
+								Token: token.New(token.Integer, pos(21), "2"),
+								Value: 2,
+							},
 						},
 					},
 				},
@@ -61,26 +70,29 @@ func TestParser_Parse_MacroDefVar_WithComment(t *testing.T) {
 	do(t, `(defvar int-list '(1 2) "a list of ints")`, []ast.Statement{
 		&ast.ExpressionStatement{
 			Expression: &ast.DefVarExpression{
-				Token: token.New(token.Identifier, 1, "defvar"),
+				Token: token.New(token.Identifier, pos(1), "defvar"),
 				Name: &ast.IdentifierExpression{
-					Token: token.New(token.Identifier, 8, "int-list"),
+					Token: token.New(token.Identifier, pos(8), "int-list"),
 					Value: "int-list",
 				},
-				Value: &ast.ListExpression{
-					Token: token.New(token.SingleQuote, 17),
-					Elements: []ast.Expression{
-						&ast.IntegerExpression{
-							Token: token.New(token.Integer, 19, "1"),
-							Value: 1,
-						},
-						&ast.IntegerExpression{
-							Token: token.New(token.Integer, 21, "2"),
-							Value: 2,
+				Value: &ast.QuoteExpression{
+					Token: token.New(token.SingleQuote, pos(17)),
+					Value: &ast.ListExpression{
+						Token: token.New(token.ParenOp, pos(18)),
+						Elements: []ast.Expression{
+							&ast.IntegerExpression{
+								Token: token.New(token.Integer, pos(19), "1"),
+								Value: 1,
+							},
+							&ast.IntegerExpression{
+								Token: token.New(token.Integer, pos(21), "2"),
+								Value: 2,
+							},
 						},
 					},
 				},
 				Comment: &ast.StringExpression{
-					Token: token.New(token.String, 24, "a list of ints"),
+					Token: token.New(token.String, pos(24), "a list of ints"),
 					Value: "a list of ints",
 				},
 			},
@@ -92,18 +104,18 @@ func TestParser_Parse_VectorOfStrings(t *testing.T) {
 	do(t, `["a" "b" "c"]`, []ast.Statement{
 		&ast.ExpressionStatement{
 			Expression: &ast.VectorExpression{
-				Token: token.New(token.BracketOp, 0),
+				Token: token.New(token.BracketOp, pos(0)),
 				Elements: []ast.Expression{
 					&ast.StringExpression{
-						Token: token.New(token.String, 1, "a"),
+						Token: token.New(token.String, pos(1), "a"),
 						Value: "a",
 					},
 					&ast.StringExpression{
-						Token: token.New(token.String, 5, "b"),
+						Token: token.New(token.String, pos(5), "b"),
 						Value: "b",
 					},
 					&ast.StringExpression{
-						Token: token.New(token.String, 9, "c"),
+						Token: token.New(token.String, pos(9), "c"),
 						Value: "c",
 					},
 				},
@@ -116,26 +128,29 @@ func TestParser_Parse_ApplyToListOfStrings(t *testing.T) {
 	do(t, `(print '("a" "b" "c"))`, []ast.Statement{
 		&ast.ExpressionStatement{
 			Expression: &ast.FunctionCall{
-				Token: token.New(token.ParenOp, 0),
+				Token: token.New(token.ParenOp, pos(0)),
 				Callee: &ast.IdentifierExpression{
-					Token: token.New(token.Identifier, 1, "print"),
+					Token: token.New(token.Identifier, pos(1), "print"),
 					Value: "print",
 				},
 				Args: []ast.Expression{
-					&ast.ListExpression{
-						Token: token.New(token.SingleQuote, 7),
-						Elements: []ast.Expression{
-							&ast.StringExpression{
-								Token: token.New(token.String, 9, "a"),
-								Value: "a",
-							},
-							&ast.StringExpression{
-								Token: token.New(token.String, 13, "b"),
-								Value: "b",
-							},
-							&ast.StringExpression{
-								Token: token.New(token.String, 17, "c"),
-								Value: "c",
+					&ast.QuoteExpression{
+						Token: token.New(token.SingleQuote, pos(7)),
+						Value: &ast.ListExpression{
+							Token: token.New(token.ParenOp, pos(8)),
+							Elements: []ast.Expression{
+								&ast.StringExpression{
+									Token: token.New(token.String, pos(9), "a"),
+									Value: "a",
+								},
+								&ast.StringExpression{
+									Token: token.New(token.String, pos(13), "b"),
+									Value: "b",
+								},
+								&ast.StringExpression{
+									Token: token.New(token.String, pos(17), "c"),
+									Value: "c",
+								},
 							},
 						},
 					},
@@ -149,27 +164,30 @@ func TestParser_Parse_List(t *testing.T) {
 	do(t, `(append '(1 2) 3)`, []ast.Statement{
 		&ast.ExpressionStatement{
 			Expression: &ast.FunctionCall{
-				Token: token.New(token.ParenOp, 0),
+				Token: token.New(token.ParenOp, pos(0)),
 				Callee: &ast.IdentifierExpression{
-					Token: token.New(token.Identifier, 1, "append"),
+					Token: token.New(token.Identifier, pos(1), "append"),
 					Value: "append",
 				},
 				Args: []ast.Expression{
-					&ast.ListExpression{
-						Token: token.New(token.SingleQuote, 8),
-						Elements: []ast.Expression{
-							&ast.IntegerExpression{
-								Token: token.New(token.Integer, 10, "1"),
-								Value: 1,
-							},
-							&ast.IntegerExpression{
-								Token: token.New(token.Integer, 12, "2"),
-								Value: 2,
+					&ast.QuoteExpression{
+						Token: token.New(token.SingleQuote, pos(8)),
+						Value: &ast.ListExpression{
+							Token: token.New(token.ParenOp, pos(9)),
+							Elements: []ast.Expression{
+								&ast.IntegerExpression{
+									Token: token.New(token.Integer, pos(10), "1"),
+									Value: 1,
+								},
+								&ast.IntegerExpression{
+									Token: token.New(token.Integer, pos(12), "2"),
+									Value: 2,
+								},
 							},
 						},
 					},
 					&ast.IntegerExpression{
-						Token: token.New(token.Integer, 15, "3"),
+						Token: token.New(token.Integer, pos(15), "3"),
 						Value: 3,
 					},
 				},
@@ -182,18 +200,18 @@ func TestParser_Parse_FunctionCall(t *testing.T) {
 	do(t, `(* 2 5)`, []ast.Statement{
 		&ast.ExpressionStatement{
 			Expression: &ast.FunctionCall{
-				Token: token.New(token.ParenOp, 0),
+				Token: token.New(token.ParenOp, pos(0)),
 				Callee: &ast.IdentifierExpression{
-					Token: token.New(token.Identifier, 1, "*"),
+					Token: token.New(token.Identifier, pos(1), "*"),
 					Value: "*",
 				},
 				Args: []ast.Expression{
 					&ast.IntegerExpression{
-						Token: token.New(token.Integer, 3, "2"),
+						Token: token.New(token.Integer, pos(3), "2"),
 						Value: 2,
 					},
 					&ast.IntegerExpression{
-						Token: token.New(token.Integer, 5, "5"),
+						Token: token.New(token.Integer, pos(5), "5"),
 						Value: 5,
 					},
 				},
@@ -206,29 +224,29 @@ func TestParser_Parse_RecFunctionCall(t *testing.T) {
 	do(t, `(* 2 (- 5 1))`, []ast.Statement{
 		&ast.ExpressionStatement{
 			Expression: &ast.FunctionCall{
-				Token: token.New(token.ParenOp, 0),
+				Token: token.New(token.ParenOp, pos(0)),
 				Callee: &ast.IdentifierExpression{
-					Token: token.New(token.Identifier, 1, "*"),
+					Token: token.New(token.Identifier, pos(1), "*"),
 					Value: "*",
 				},
 				Args: []ast.Expression{
 					&ast.IntegerExpression{
-						Token: token.New(token.Integer, 3, "2"),
+						Token: token.New(token.Integer, pos(3), "2"),
 						Value: 2,
 					},
 					&ast.FunctionCall{
-						Token: token.New(token.ParenOp, 5),
+						Token: token.New(token.ParenOp, pos(5)),
 						Callee: &ast.IdentifierExpression{
-							Token: token.New(token.Identifier, 6, "-"),
+							Token: token.New(token.Identifier, pos(6), "-"),
 							Value: "-",
 						},
 						Args: []ast.Expression{
 							&ast.IntegerExpression{
-								Token: token.New(token.Integer, 8, "5"),
+								Token: token.New(token.Integer, pos(8), "5"),
 								Value: 5,
 							},
 							&ast.IntegerExpression{
-								Token: token.New(token.Integer, 10, "1"),
+								Token: token.New(token.Integer, pos(10), "1"),
 								Value: 1,
 							},
 						},
@@ -238,3 +256,368 @@ func TestParser_Parse_RecFunctionCall(t *testing.T) {
 		},
 	})
 }
+
+func TestParser_Parse_MacroDefun(t *testing.T) {
+	do(t, `(defun sq (x) "squares x" (* x x))`, []ast.Statement{
+		&ast.ExpressionStatement{
+			Expression: &ast.DefunExpression{
+				Token: token.New(token.Identifier, pos(1), "defun"),
+				Name: &ast.IdentifierExpression{
+					Token: token.New(token.Identifier, pos(7), "sq"),
+					Value: "sq",
+				},
+				Params: []*ast.IdentifierExpression{
+					{
+						Token: token.New(token.Identifier, pos(11), "x"),
+						Value: "x",
+					},
+				},
+				Comment: &ast.StringExpression{
+					Token: token.New(token.String, pos(14), "squares x"),
+					Value: "squares x",
+				},
+				Body: []ast.Expression{
+					&ast.FunctionCall{
+						Token: token.New(token.ParenOp, pos(26)),
+						Callee: &ast.IdentifierExpression{
+							Token: token.New(token.Identifier, pos(27), "*"),
+							Value: "*",
+						},
+						Args: []ast.Expression{
+							&ast.IdentifierExpression{
+								Token: token.New(token.Identifier, pos(29), "x"),
+								Value: "x",
+							},
+							&ast.IdentifierExpression{
+								Token: token.New(token.Identifier, pos(31), "x"),
+								Value: "x",
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestParser_Parse_LambdaCall(t *testing.T) {
+	do(t, `((lambda (x) x) 5)`, []ast.Statement{
+		&ast.ExpressionStatement{
+			Expression: &ast.FunctionCall{
+				Token: token.New(token.ParenOp, pos(0)),
+				Callee: &ast.LambdaExpression{
+					Token: token.New(token.Identifier, pos(2), "lambda"),
+					Params: []*ast.IdentifierExpression{
+						{
+							Token: token.New(token.Identifier, pos(10), "x"),
+							Value: "x",
+						},
+					},
+					Body: []ast.Expression{
+						&ast.IdentifierExpression{
+							Token: token.New(token.Identifier, pos(13), "x"),
+							Value: "x",
+						},
+					},
+				},
+				Args: []ast.Expression{
+					&ast.IntegerExpression{
+						Token: token.New(token.Integer, pos(16), "5"),
+						Value: 5,
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestParser_Parse_MacroIf(t *testing.T) {
+	do(t, `(if x 1 2)`, []ast.Statement{
+		&ast.ExpressionStatement{
+			Expression: &ast.IfExpression{
+				Token: token.New(token.Identifier, pos(1), "if"),
+				Condition: &ast.IdentifierExpression{
+					Token: token.New(token.Identifier, pos(4), "x"),
+					Value: "x",
+				},
+				Consequence: &ast.IntegerExpression{
+					Token: token.New(token.Integer, pos(6), "1"),
+					Value: 1,
+				},
+				Alternative: &ast.IntegerExpression{
+					Token: token.New(token.Integer, pos(8), "2"),
+					Value: 2,
+				},
+			},
+		},
+	})
+}
+
+func TestParser_Parse_MacroCallArgsAreData(t *testing.T) {
+	do(t, "(defmacro m (x) x) (m (a 1))", []ast.Statement{
+		&ast.ExpressionStatement{
+			Expression: &ast.DefmacroExpression{
+				Token: token.New(token.Identifier, pos(1), "defmacro"),
+				Name: &ast.IdentifierExpression{
+					Token: token.New(token.Identifier, pos(10), "m"),
+					Value: "m",
+				},
+				Params: []*ast.IdentifierExpression{
+					{
+						Token: token.New(token.Identifier, pos(13), "x"),
+						Value: "x",
+					},
+				},
+				Body: []ast.Expression{
+					&ast.IdentifierExpression{
+						Token: token.New(token.Identifier, pos(16), "x"),
+						Value: "x",
+					},
+				},
+			},
+		},
+		&ast.ExpressionStatement{
+			Expression: &ast.MacroCallExpression{
+				Token: token.New(token.Identifier, pos(20), "m"),
+				Name: &ast.IdentifierExpression{
+					Token: token.New(token.Identifier, pos(20), "m"),
+					Value: "m",
+				},
+				Args: []ast.Expression{
+					&ast.ListExpression{
+						Token: token.New(token.ParenOp, pos(22)),
+						Elements: []ast.Expression{
+							&ast.IdentifierExpression{
+								Token: token.New(token.Identifier, pos(23), "a"),
+								Value: "a",
+							},
+							&ast.IntegerExpression{
+								Token: token.New(token.Integer, pos(25), "1"),
+								Value: 1,
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestParser_Parse_UnquoteOutsideOfQuasiQuote(t *testing.T) {
+	_, err := New(scanner.New("(defmacro m (x) x) (m ,x)")).Parse()
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestParser_Parse_ErrorPosition(t *testing.T) {
+	_, err := New(scanner.NewFile("broken.gl", "(print 1)\n(defvar 5 6)")).Parse()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	expected := "broken.gl:2:9: expected token Identifier, got Integer"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestParser_Parse_ErrorIsSyntaxError(t *testing.T) {
+	_, err := New(scanner.New("(defvar)")).Parse()
+	if l, ok := err.(diag.List); !ok || len(l) != 1 || l[0].Kind != diag.Syntax {
+		t.Errorf("expected a syntax error, got %#v", err)
+	}
+}
+
+func TestParser_Parse_ReportsAllErrors(t *testing.T) {
+	source := "(defvar 1 2)\n(print 1)\n(defun (x) x)\n)\n(lambda [x] x)\n(print 2)\n" +
+		"(print (+ 1 2] 3)\n{:a (+ 1 2}}"
+	program, err := New(scanner.NewFile("broken.gl", source)).Parse()
+
+	l, ok := err.(diag.List)
+	if !ok {
+		t.Fatalf("expected a list of errors, got %#v", err)
+	}
+	expected := []string{
+		"broken.gl:1:9: expected token Identifier, got Integer",
+		"broken.gl:3:8: expected token Identifier, got ParenOp<(>",
+		"broken.gl:4:1: no infix parser for ParenCl<)>",
+		"broken.gl:5:9: expected token ParenOp<(>, got BracketOp<[>",
+		"broken.gl:7:14: expected token ParenCl<)>, got BracketCl<]>",
+		"broken.gl:8:11: expected token ParenCl<)>, got BraceCl<}>",
+	}
+	if len(l) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %s", len(expected), len(l), err)
+	}
+	for i, e := range l {
+		if e.Error() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], e.Error())
+		}
+	}
+
+	// the well-formed forms are kept
+	if program == nil || len(program.Statements) != 2 {
+		t.Fatalf("expected a partial program of 2 statements, got %#v", program)
+	}
+	for i, lit := range []string{"(print 1)", "(print 2)"} {
+		if s := program.Statements[i].String(); s != lit {
+			t.Errorf("expected %s, got %s", lit, s)
+		}
+	}
+}
+
+func TestParser_Parse_ErrorAtEOF(t *testing.T) {
+	program, err := New(scanner.New("(print 1) (print (+ 1 2)")).Parse()
+	if l, ok := err.(diag.List); !ok || len(l) != 1 {
+		t.Fatalf("expected a single error, got %#v", err)
+	}
+	if len(program.Statements) != 1 {
+		t.Errorf("expected 1 statement, got %d", len(program.Statements))
+	}
+}
+
+func TestParser_Parse_IllegalTokens(t *testing.T) {
+	tests := map[string]string{
+		`(print "abc`:       `1:8: unterminated string`,
+		`(print "a\q") 1`:   `1:10: invalid escape sequence \q`,
+		`(print 1) #| open`: `1:11: unterminated block comment`,
+	}
+
+	for source, expected := range tests {
+		_, err := New(scanner.New(source)).Parse()
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestParser_Parse_Numbers(t *testing.T) {
+	ints := map[string]int64{
+		"-5":                   -5,
+		"1_000_000":            1000000,
+		"0xff":                 255,
+		"-0x10":                -16,
+		"0o17":                 15,
+		"0b1010":               10,
+		"010":                  10,
+		"9223372036854775807":  9223372036854775807,
+		"-9223372036854775808": -9223372036854775808,
+	}
+	for source, expected := range ints {
+		program, err := New(scanner.New(source)).Parse()
+		if err != nil {
+			t.Errorf("%s: %s", source, err)
+			continue
+		}
+		ie := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerExpression)
+		if ie.Value != expected {
+			t.Errorf("%s: expected %d, got %d", source, expected, ie.Value)
+		}
+	}
+
+	floats := map[string]float64{"1e9": 1e9, "2.5E-3": 2.5e-3, "-1_0.5": -10.5}
+	for source, expected := range floats {
+		program, err := New(scanner.New(source)).Parse()
+		if err != nil {
+			t.Errorf("%s: %s", source, err)
+			continue
+		}
+		fe := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FloatExpression)
+		if fe.Value != expected {
+			t.Errorf("%s: expected %f, got %f", source, expected, fe.Value)
+		}
+	}
+}
+
+func TestParser_Parse_BigIntegers(t *testing.T) {
+	tests := map[string]string{
+		"9223372036854775808":        "9223372036854775808",
+		"-9_223_372_036_854_775_809": "-9223372036854775809",
+		"0xffffffffffffffff":         "18446744073709551615",
+	}
+	for source, expected := range tests {
+		program, err := New(scanner.New(source)).Parse()
+		if err != nil {
+			t.Errorf("%s: %s", source, err)
+			continue
+		}
+		be, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.BigIntegerExpression)
+		if !ok {
+			t.Errorf("%s: expected a big integer", source)
+			continue
+		}
+		if be.Value.String() != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, be.Value)
+		}
+	}
+}
+
+func TestParser_Parse_NumberErrors(t *testing.T) {
+	tests := map[string]string{
+		"1e400": "1:1: float literal 1e400 overflows float64",
+		"1__0":  "1:1: malformed number 1__0",
+	}
+	for source, expected := range tests {
+		_, err := New(scanner.New(source)).Parse()
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestParser_Parse_QuoteForm(t *testing.T) {
+	do(t, "(quote x)", []ast.Statement{
+		&ast.ExpressionStatement{
+			Expression: &ast.QuoteExpression{
+				Token: token.New(token.Identifier, pos(1), "quote"),
+				Value: &ast.IdentifierExpression{
+					Token: token.New(token.Identifier, pos(7), "x"),
+					Value: "x",
+				},
+			},
+		},
+	})
+}
+
+func TestParser_Parse_UnquoteInsideOfQuote(t *testing.T) {
+	if _, err := New(scanner.New("'(a ,b)")).Parse(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestParser_Parse_Map(t *testing.T) {
+	do(t, `{:a 1 "b" x}`, []ast.Statement{
+		&ast.ExpressionStatement{
+			Expression: &ast.MapExpression{
+				Token: token.New(token.BraceOp, pos(0)),
+				Keys: []ast.Expression{
+					&ast.KeywordExpression{
+						Token: token.New(token.Keyword, pos(1), "a"),
+						Value: "a",
+					},
+					&ast.StringExpression{
+						Token: token.New(token.String, pos(6), "b"),
+						Value: "b",
+					},
+				},
+				Values: []ast.Expression{
+					&ast.IntegerExpression{
+						Token: token.New(token.Integer, pos(4), "1"),
+						Value: 1,
+					},
+					&ast.IdentifierExpression{
+						Token: token.New(token.Identifier, pos(10), "x"),
+						Value: "x",
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestParser_Parse_MapOfOddLength(t *testing.T) {
+	_, err := New(scanner.New("{:a 1 :b} (print 1)")).Parse()
+	expected := "1:9: map literal expects an even number of forms, 3 given"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
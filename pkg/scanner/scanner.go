@@ -1,37 +1,82 @@
 package scanner
 
 import (
+	"sort"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/pmukhin/glisp/pkg/token"
 )
 
+// identifierPunct holds non-letter runes identifiers may consist of
+const identifierPunct = "<>=*/+-!?%&._$^~"
+
+// isIdentifier tells if an identifier may start with ch,
+// a sign followed by a digit starts a number instead
 func isIdentifier(ch rune) bool {
-	return unicode.IsLetter(ch) ||
-		ch == '<' ||
-		ch == '>' ||
-		ch == '=' ||
-		ch == '*' ||
-		ch == '/' ||
-		ch == '+' ||
-		ch == '-'
+	return unicode.IsLetter(ch) || strings.ContainsRune(identifierPunct, ch)
+}
+
+// isIdentifierPart tells if ch may go after the first rune of an identifier
+func isIdentifierPart(ch rune) bool {
+	return isIdentifier(ch) || unicode.IsDigit(ch)
 }
 
 type Scanner struct {
-	src    []rune
-	ch     rune
-	offset int
+	src      []rune
+	ch       rune
+	offset   int
+	filename string
+	// lineStarts holds offsets lines start at
+	lineStarts []int
+	// keepComments makes Next return comments as token.Comment
+	keepComments bool
 }
 
 func New(source string) *Scanner {
+	return NewFile("", source)
+}
+
+// NewFile constructs a Scanner putting filename into token positions
+func NewFile(filename, source string) *Scanner {
 	s := new(Scanner)
 	s.src = []rune(source)
 	s.ch = -1
 	s.offset = -1
+	s.filename = filename
+
+	s.lineStarts = []int{0}
+	for i, ch := range s.src {
+		if ch == '\n' {
+			s.lineStarts = append(s.lineStarts, i+1)
+		}
+	}
 
 	return s
 }
 
+// pos makes a position of a given offset
+func (s *Scanner) pos(offset int) token.Position {
+	line := sort.Search(len(s.lineStarts), func(i int) bool {
+		return s.lineStarts[i] > offset
+	})
+	return token.Position{
+		Filename: s.filename,
+		Offset:   offset,
+		Line:     line,
+		Column:   offset - s.lineStarts[line-1] + 1,
+	}
+}
+
+// SetKeepComments makes the scanner return comments as tokens
+// instead of skipping them, it's for tools like formatters,
+// the parser doesn't expect comment tokens
+func (s *Scanner) SetKeepComments(keep bool) {
+	s.keepComments = keep
+}
+
 func (s *Scanner) nextChar() {
 	s.offset++
 	if s.offset >= len(s.src) {
@@ -41,14 +86,22 @@ func (s *Scanner) nextChar() {
 	}
 }
 
+// Next returns the next token skipping comments unless they're kept
 func (s *Scanner) Next() token.Token {
+	if s.keepComments {
+		return s.scan()
+	}
+	return s.nextNonComment()
+}
+
+func (s *Scanner) scan() token.Token {
 	s.nextChar()
 	s.skipWhitespace()
 
 	tokType := token.Illegal
 	switch s.ch {
 	case -1:
-		return token.New(token.EOF, s.offset, "")
+		return token.New(token.EOF, s.pos(s.offset), "")
 	case '(':
 		tokType = token.ParenOp
 	case ')':
@@ -57,31 +110,60 @@ func (s *Scanner) Next() token.Token {
 		tokType = token.BracketOp
 	case ']':
 		tokType = token.BracketCl
+	case '{':
+		tokType = token.BraceOp
+	case '}':
+		tokType = token.BraceCl
 	case '"':
 		return s.scanString()
 	case '\'':
 		tokType = token.SingleQuote
+	case '`':
+		tokType = token.Backquote
+	case ',':
+		if s.peek() == '@' {
+			pos := s.pos(s.offset)
+			s.nextChar() // eat `@`
+			return token.New(token.CommaAt, pos)
+		}
+		tokType = token.Comma
 	case ':':
+		if isIdentifierPart(s.peek()) {
+			return s.scanKeyword()
+		}
 		tokType = token.Colon
+	case ';':
+		return s.scanLineComment()
+	case '#':
+		switch s.peek() {
+		case '|':
+			return s.scanBlockComment()
+		case ';':
+			return s.scanDatumComment()
+		case '\\':
+			return s.scanRune()
+		}
+		return token.New(token.Illegal, s.pos(s.offset), string(s.ch))
 	default:
 		switch true {
-		case unicode.IsDigit(s.ch):
+		case unicode.IsDigit(s.ch),
+			(s.ch == '-' || s.ch == '+') && isDecimal(s.peek()):
 			return s.scanNumber()
 		case isIdentifier(s.ch):
 			return s.scanIdentifier()
 		default:
-			return token.New(token.Illegal, s.offset, string(s.ch))
+			return token.New(token.Illegal, s.pos(s.offset), string(s.ch))
 		}
 	}
 
-	return token.New(tokType, s.offset)
+	return token.New(tokType, s.pos(s.offset))
 }
 
 func (s *Scanner) scanIdentifier() token.Token {
-	pos := s.offset // preserve the position
+	pos := s.pos(s.offset) // preserve the position
 	str := make([]rune, 0, 32)
 
-	for isIdentifier(s.ch) {
+	for isIdentifierPart(s.ch) {
 		str = append(str, s.ch)
 		s.nextChar()
 	}
@@ -90,44 +172,355 @@ func (s *Scanner) scanIdentifier() token.Token {
 	return token.New(token.Identifier, pos, string(str))
 }
 
+// scanKeyword scans :name, the literal of the token is the name
+func (s *Scanner) scanKeyword() token.Token {
+	pos := s.pos(s.offset) // preserve the position
+	s.nextChar()           // eat `:`
+	tok := s.scanIdentifier()
+
+	return token.New(token.Keyword, pos, tok.Literal)
+}
+
 func (s *Scanner) skipWhitespace() {
 	for s.ch == '\n' || s.ch == ' ' || s.ch == '\r' || s.ch == '\t' {
 		s.nextChar()
 	}
 }
 
+// escapes maps escape sequences of strings to runes they stand for
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'"':  '"',
+	'\\': '\\',
+}
+
+// scanString scans a string literal decoding escape sequences, the literal
+// of the token is the decoded value. An unterminated string is Illegal
+// at the opening quote and a string with a wrong escape sequence
+// is Illegal at the sequence
 func (s *Scanner) scanString() token.Token {
-	pos := s.offset // preserve the position
-	s.nextChar()    // eat `"`
+	if s.peekN(1) == '"' && s.peekN(2) == '"' {
+		return s.scanRawString()
+	}
+
+	pos := s.pos(s.offset) // preserve the position
+	s.nextChar()           // eat `"`
 	str := make([]rune, 0, 32)
+	var illegal *token.Token
 
 	for s.ch != '"' {
-		str = append(str, s.ch)
+		switch s.ch {
+		case -1:
+			s.un()
+			return token.New(token.Illegal, pos, `"`)
+		case '\\':
+			start := s.offset
+			ch, ok := s.scanEscape()
+			if !ok && illegal == nil {
+				tok := token.New(token.Illegal, s.pos(start), string(s.src[start:s.offset+1]))
+				illegal = &tok
+			}
+			str = append(str, ch)
+		default:
+			str = append(str, s.ch)
+		}
 		s.nextChar()
 	}
 
+	if illegal != nil {
+		return *illegal
+	}
 	return token.New(token.String, pos, string(str))
 }
 
+// scanEscape scans an escape sequence returning the rune it stands for,
+// it's false if the sequence is wrong
+func (s *Scanner) scanEscape() (rune, bool) {
+	s.nextChar() // eat `\`
+
+	if ch, ok := escapes[s.ch]; ok {
+		return ch, true
+	}
+	if s.ch != 'u' || s.peek() != '{' {
+		if s.ch == -1 {
+			s.un()
+		}
+		return utf8.RuneError, false
+	}
+
+	return s.scanCodePoint()
+}
+
+// scanCodePoint scans u{...} having a hex code point in braces
+func (s *Scanner) scanCodePoint() (rune, bool) {
+	s.nextChar() // eat `u`
+	digits := make([]rune, 0, 6)
+	for unicode.Is(unicode.ASCII_Hex_Digit, s.peek()) {
+		s.nextChar()
+		digits = append(digits, s.ch)
+	}
+	if s.peek() != '}' {
+		return utf8.RuneError, false
+	}
+	s.nextChar() // eat `}`
+
+	code, err := strconv.ParseUint(string(digits), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return utf8.RuneError, false
+	}
+	return rune(code), true
+}
+
+// scanRawString scans a `"""` string, it may span lines
+// and has no escape sequences
+func (s *Scanner) scanRawString() token.Token {
+	pos := s.pos(s.offset) // preserve the position
+	s.nextChar()
+	s.nextChar() // eat `"""`
+	start := s.offset + 1
+
+	for {
+		s.nextChar()
+		if s.ch == -1 {
+			s.un()
+			return token.New(token.Illegal, pos, `"""`)
+		}
+		if s.ch == '"' && s.peekN(1) == '"' && s.peekN(2) == '"' {
+			break
+		}
+	}
+	str := string(s.src[start:s.offset])
+	s.nextChar()
+	s.nextChar() // eat `""`
+
+	return token.New(token.String, pos, str)
+}
+
+// runeNames maps names of characters to the characters
+var runeNames = map[string]rune{
+	"newline":   '\n',
+	"space":     ' ',
+	"tab":       '\t',
+	"return":    '\r',
+	"nul":       0,
+	"alarm":     '\a',
+	"backspace": '\b',
+	"delete":    0x7f,
+	"escape":    0x1b,
+}
+
+// scanRune scans a character literal: #\a, #\λ, #\newline or #\u{3bb},
+// the literal of the token is the character itself
+func (s *Scanner) scanRune() token.Token {
+	start := s.offset
+	pos := s.pos(start)
+	s.nextChar() // eat `\`
+	s.nextChar()
+
+	if s.ch == -1 {
+		s.un()
+		return token.New(token.Illegal, pos, "#\\")
+	}
+	if s.ch == 'u' && s.peek() == '{' {
+		if ch, ok := s.scanCodePoint(); ok {
+			return token.New(token.Rune, pos, string(ch))
+		}
+		return token.New(token.Illegal, pos, string(s.src[start:s.offset+1]))
+	}
+
+	// a name goes on while there are letters, #\( is a single rune
+	ch := s.ch
+	for unicode.IsLetter(ch) && unicode.IsLetter(s.peek()) {
+		s.nextChar()
+	}
+	if s.offset == start+2 {
+		return token.New(token.Rune, pos, string(ch))
+	}
+
+	name := string(s.src[start+2 : s.offset+1])
+	if named, ok := runeNames[name]; ok {
+		return token.New(token.Rune, pos, string(named))
+	}
+	return token.New(token.Illegal, pos, string(s.src[start:s.offset+1]))
+}
+
+// scanLineComment scans a comment starting with `;` up to the end of line
+func (s *Scanner) scanLineComment() token.Token {
+	start := s.offset
+
+	for s.peek() != '\n' && s.peek() != -1 {
+		s.nextChar()
+	}
+
+	return token.New(token.Comment, s.pos(start), string(s.src[start:s.offset+1]))
+}
+
+// scanBlockComment scans a `#| ... |#` comment, block comments nest
+func (s *Scanner) scanBlockComment() token.Token {
+	start := s.offset
+	s.nextChar() // eat `|`
+
+	for depth := 1; depth > 0; {
+		s.nextChar()
+		switch {
+		case s.ch == -1:
+			s.un()
+			return token.New(token.Illegal, s.pos(start), "#|")
+		case s.ch == '|' && s.peek() == '#':
+			s.nextChar() // eat `#`
+			depth--
+		case s.ch == '#' && s.peek() == '|':
+			s.nextChar() // eat `|`
+			depth++
+		}
+	}
+
+	return token.New(token.Comment, s.pos(start), string(s.src[start:s.offset+1]))
+}
+
+// scanDatumComment scans `#;` along with the datum following it
+func (s *Scanner) scanDatumComment() token.Token {
+	start := s.offset
+	s.nextChar() // eat `;`
+
+	if illegal, ok := s.skipDatum(); !ok {
+		if illegal.Type == token.Illegal {
+			// the datum is broken itself, let the parser tell why
+			return illegal
+		}
+		return token.New(token.Illegal, s.pos(start), "#;")
+	}
+
+	return token.New(token.Comment, s.pos(start), string(s.src[start:s.offset+1]))
+}
+
+// skipDatum scans a single datum, it's false if source ends before the
+// datum does or the enclosing form ends before the datum starts. An
+// Illegal token met within the datum is returned along with false
+func (s *Scanner) skipDatum() (token.Token, bool) {
+	tok := s.nextNonComment()
+
+	switch tok.Type {
+	case token.Illegal:
+		return tok, false
+	case token.EOF, token.ParenCl, token.BracketCl, token.BraceCl:
+		// leave the closer to the form it belongs to
+		s.un()
+		return tok, false
+	case token.SingleQuote, token.Backquote, token.Comma, token.CommaAt:
+		return s.skipDatum()
+	case token.ParenOp, token.BracketOp, token.BraceOp:
+		for depth := 1; depth > 0; {
+			switch tok := s.nextNonComment(); tok.Type {
+			case token.Illegal:
+				return tok, false
+			case token.EOF:
+				s.un()
+				return tok, false
+			case token.ParenOp, token.BracketOp, token.BraceOp:
+				depth++
+			case token.ParenCl, token.BracketCl, token.BraceCl:
+				depth--
+			}
+		}
+	}
+
+	return tok, true
+}
+
+func (s *Scanner) nextNonComment() token.Token {
+	for {
+		tok := s.scan()
+		if tok.Type != token.Comment {
+			return tok
+		}
+	}
+}
+
+func isDecimal(ch rune) bool { return '0' <= ch && ch <= '9' }
+func isHex(ch rune) bool     { return unicode.Is(unicode.ASCII_Hex_Digit, ch) }
+func isOctal(ch rune) bool   { return '0' <= ch && ch <= '7' }
+func isBinary(ch rune) bool  { return ch == '0' || ch == '1' }
+
+// prefix2digits maps base prefixes to digits allowed after them
+var prefix2digits = map[rune]func(rune) bool{
+	'x': isHex,
+	'X': isHex,
+	'o': isOctal,
+	'O': isOctal,
+	'b': isBinary,
+	'B': isBinary,
+}
+
+// scanNumber scans an optionally signed number: 42, -1_000, 0xff, 0o17,
+// 0b1010, 1.5, 1e9 or 2.5E-3. The literal is kept as it is in source,
+// a malformed number is Illegal as a whole
 func (s *Scanner) scanNumber() token.Token {
-	pos := s.offset // preserve offset
+	start := s.offset
+	pos := s.pos(start) // preserve offset
 	typ := token.Integer
-	str := make([]rune, 0, 8)
 
-	for s.ch == '.' || unicode.IsDigit(s.ch) {
-		if s.ch == '.' {
-			if typ == token.Float {
-				return token.New(token.Illegal, s.offset, string(s.ch))
-			}
+	if s.ch == '-' || s.ch == '+' {
+		s.nextChar() // eat sign
+	}
+
+	isDigit := isDecimal
+	prefixed := false
+	if s.ch == '0' {
+		if digits, ok := prefix2digits[s.peek()]; ok {
+			s.nextChar()
+			s.nextChar() // eat prefix
+			isDigit, prefixed = digits, true
+		}
+	}
+
+	ok := s.scanDigits(isDigit)
+	if !prefixed {
+		if s.ch == '.' && isDecimal(s.peek()) {
+			typ = token.Float
+			s.nextChar() // eat `.`
+			ok = s.scanDigits(isDecimal) && ok
+		}
+		if s.ch == 'e' || s.ch == 'E' {
 			typ = token.Float
+			s.nextChar() // eat `e`
+			if s.ch == '-' || s.ch == '+' {
+				s.nextChar() // eat sign
+			}
+			ok = s.scanDigits(isDecimal) && ok
 		}
+	}
 
-		str = append(str, s.ch)
+	// a number running into something else, e.g. 12ab or 1.2.3
+	for isIdentifierPart(s.ch) {
+		ok = false
 		s.nextChar()
 	}
 	s.un()
 
-	return token.New(typ, pos, string(str))
+	lit := string(s.src[start : s.offset+1])
+	if !ok {
+		return token.New(token.Illegal, pos, lit)
+	}
+	return token.New(typ, pos, lit)
+}
+
+// scanDigits scans digits separated by single underscores,
+// it's false if there're no digits or underscores are misplaced
+func (s *Scanner) scanDigits(isDigit func(rune) bool) bool {
+	n, ok := 0, true
+	for isDigit(s.ch) || s.ch == '_' {
+		if s.ch == '_' {
+			ok = ok && n > 0 && isDigit(s.peek())
+		} else {
+			n++
+		}
+		s.nextChar()
+	}
+	return ok && n > 0
 }
 
 func (s *Scanner) peek() rune {
@@ -137,6 +530,14 @@ func (s *Scanner) peek() rune {
 	return s.ch
 }
 
+// peekN returns the rune n positions ahead of the current one
+func (s *Scanner) peekN(n int) rune {
+	if s.offset+n >= len(s.src) {
+		return -1
+	}
+	return s.src[s.offset+n]
+}
+
 func (s *Scanner) un() {
 	s.offset--
 	s.ch = s.src[s.offset]
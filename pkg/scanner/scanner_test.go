@@ -3,11 +3,16 @@ package scanner
 import (
 	"testing"
 
-	"github.com/pmukhin/glisp/pkg/token"
 	"fmt"
+	"github.com/pmukhin/glisp/pkg/token"
 	"reflect"
 )
 
+// pos makes a position of a given offset in a single line source
+func pos(offset int) token.Position {
+	return token.Position{Offset: offset, Line: 1, Column: offset + 1}
+}
+
 // test template
 func doTest(t *testing.T, input string, expected []token.Type) {
 	scn := New(input)
@@ -34,7 +39,7 @@ func doTest(t *testing.T, input string, expected []token.Type) {
 	for i := 0; i < len(expected); i++ {
 		if tokens[i].Type != expected[i] {
 			t.Errorf(
-				"%d: expected token of type %v, got %v in pos %d",
+				"%d: expected token of type %v, got %v in pos %s",
 				i,
 				expected[i],
 				tokens[i].Type,
@@ -63,37 +68,37 @@ func do(t *testing.T, input string, expected []token.Token) {
 
 func TestScanner_Next_ScanDefVar(t *testing.T) {
 	do(t, `(defvar int-list '(1 2 3) "a list of ints")`, []token.Token{
-		token.New(token.ParenOp, 0, "("),
-		token.New(token.Identifier, 1, "defvar"),
-		token.New(token.Identifier, 8, "int-list"),
-		token.New(token.SingleQuote, 17),
-		token.New(token.ParenOp, 18, "("),
-		token.New(token.Integer, 19, "1"),
-		token.New(token.Integer, 21, "2"),
-		token.New(token.Integer, 23, "3"),
-		token.New(token.ParenCl, 24, ")"),
-		token.New(token.String, 26, "a list of ints"),
-		token.New(token.ParenCl, 42, ")"),
+		token.New(token.ParenOp, pos(0), "("),
+		token.New(token.Identifier, pos(1), "defvar"),
+		token.New(token.Identifier, pos(8), "int-list"),
+		token.New(token.SingleQuote, pos(17)),
+		token.New(token.ParenOp, pos(18), "("),
+		token.New(token.Integer, pos(19), "1"),
+		token.New(token.Integer, pos(21), "2"),
+		token.New(token.Integer, pos(23), "3"),
+		token.New(token.ParenCl, pos(24), ")"),
+		token.New(token.String, pos(26), "a list of ints"),
+		token.New(token.ParenCl, pos(42), ")"),
 	})
 }
 
 func TestScanner_Next_4(t *testing.T) {
 	do(t, `(+ "test" "b")`, []token.Token{
-		token.New(token.ParenOp, 0, "("),
-		token.New(token.Identifier, 1, "+"),
-		token.New(token.String, 3, "test"),
-		token.New(token.String, 10, "b"),
-		token.New(token.ParenCl, 13, ")"),
+		token.New(token.ParenOp, pos(0), "("),
+		token.New(token.Identifier, pos(1), "+"),
+		token.New(token.String, pos(3), "test"),
+		token.New(token.String, pos(10), "b"),
+		token.New(token.ParenCl, pos(13), ")"),
 	})
 }
 
 func TestScanner_Next_3(t *testing.T) {
 	do(t, `(+ 5.545 24)`, []token.Token{
-		token.New(token.ParenOp, 0, "("),
-		token.New(token.Identifier, 1, "+"),
-		token.New(token.Float, 3, "5.545"),
-		token.New(token.Integer, 9, "24"),
-		token.New(token.ParenCl, 11, ")"),
+		token.New(token.ParenOp, pos(0), "("),
+		token.New(token.Identifier, pos(1), "+"),
+		token.New(token.Float, pos(3), "5.545"),
+		token.New(token.Integer, pos(9), "24"),
+		token.New(token.ParenCl, pos(11), ")"),
 	})
 }
 
@@ -145,3 +150,256 @@ func TestScanner_Next_Vector(t *testing.T) {
 		token.ParenCl,
 	})
 }
+
+func TestScanner_Next_QuasiQuote(t *testing.T) {
+	do(t, "`(a ,b ,@c)", []token.Token{
+		token.New(token.Backquote, pos(0)),
+		token.New(token.ParenOp, pos(1)),
+		token.New(token.Identifier, pos(2), "a"),
+		token.New(token.Comma, pos(4)),
+		token.New(token.Identifier, pos(5), "b"),
+		token.New(token.CommaAt, pos(7)),
+		token.New(token.Identifier, pos(9), "c"),
+		token.New(token.ParenCl, pos(10)),
+	})
+}
+
+func TestScanner_Next_LinesAndColumns(t *testing.T) {
+	scn := NewFile("lines.gl", "(print\n  \"a\"\n\n x)")
+	expected := []token.Position{
+		{Filename: "lines.gl", Offset: 0, Line: 1, Column: 1},
+		{Filename: "lines.gl", Offset: 1, Line: 1, Column: 2},
+		{Filename: "lines.gl", Offset: 9, Line: 2, Column: 3},
+		{Filename: "lines.gl", Offset: 15, Line: 4, Column: 2},
+		{Filename: "lines.gl", Offset: 16, Line: 4, Column: 3},
+		{Filename: "lines.gl", Offset: 17, Line: 4, Column: 4},
+	}
+
+	for i, exp := range expected {
+		tok := scn.Next()
+		if tok.Pos != exp {
+			t.Errorf("%d: expected %s (%d), got %s (%d)", i, exp, exp.Offset, tok.Pos, tok.Pos.Offset)
+		}
+	}
+	if tok := scn.Next(); tok.Type != token.EOF || tok.Pos.String() != "lines.gl:4:5" {
+		t.Errorf("expected EOF at lines.gl:4:5, got %s at %s", tok.Type, tok.Pos)
+	}
+}
+
+func TestScanner_Next_SkipsComments(t *testing.T) {
+	do(t, "; a line\n(a #| block #| nested |# |# b) #;(c [d]) e #;'f", []token.Token{
+		token.New(token.ParenOp, token.Position{Offset: 9, Line: 2, Column: 1}),
+		token.New(token.Identifier, token.Position{Offset: 10, Line: 2, Column: 2}, "a"),
+		token.New(token.Identifier, token.Position{Offset: 37, Line: 2, Column: 29}, "b"),
+		token.New(token.ParenCl, token.Position{Offset: 38, Line: 2, Column: 30}),
+		token.New(token.Identifier, token.Position{Offset: 50, Line: 2, Column: 42}, "e"),
+	})
+}
+
+func TestScanner_Next_KeepsComments(t *testing.T) {
+	scn := New("a ; one\n#| two |# #;(b) c")
+	scn.SetKeepComments(true)
+
+	expected := []token.Token{
+		token.New(token.Identifier, pos(0), "a"),
+		token.New(token.Comment, pos(2), "; one"),
+		token.New(token.Comment, token.Position{Offset: 8, Line: 2, Column: 1}, "#| two |#"),
+		token.New(token.Comment, token.Position{Offset: 18, Line: 2, Column: 11}, "#;(b)"),
+		token.New(token.Identifier, token.Position{Offset: 24, Line: 2, Column: 17}, "c"),
+		token.New(token.EOF, token.Position{Offset: 25, Line: 2, Column: 18}, ""),
+	}
+	for i, exp := range expected {
+		if tok := scn.Next(); !reflect.DeepEqual(tok, exp) {
+			t.Errorf("%d: expected %v, got %v", i, exp, tok)
+		}
+	}
+}
+
+func TestScanner_Next_UnterminatedComments(t *testing.T) {
+	for _, input := range []string{"#| a #| b |#", "#;(a b"} {
+		scn := New(input)
+		if tok := scn.Next(); tok.Type != token.Illegal || tok.Pos != pos(0) {
+			t.Errorf("%q: expected Illegal at 1:1, got %s at %s", input, tok.Type, tok.Pos)
+		}
+		if tok := scn.Next(); tok.Type != token.EOF {
+			t.Errorf("%q: expected EOF, got %s", input, tok.Type)
+		}
+	}
+}
+
+func TestScanner_Next_DatumCommentBeforeCloser(t *testing.T) {
+	do(t, "(a #;)", []token.Token{
+		token.New(token.ParenOp, pos(0)),
+		token.New(token.Identifier, pos(1), "a"),
+		token.New(token.Illegal, pos(3), "#;"),
+		token.New(token.ParenCl, pos(5)),
+	})
+	do(t, "[#; ]", []token.Token{
+		token.New(token.BracketOp, pos(0)),
+		token.New(token.Illegal, pos(1), "#;"),
+		token.New(token.BracketCl, pos(4)),
+	})
+}
+
+func TestScanner_Next_DatumCommentOfIllegal(t *testing.T) {
+	tests := map[string]token.Token{
+		"(print 1) #; #| oops\n(print 2)": token.New(token.Illegal, pos(13), "#|"),
+		"(print 1) #; \"oops\n(print 2)":  token.New(token.Illegal, pos(13), `"`),
+		"#;(a \"b":                        token.New(token.Illegal, pos(5), `"`),
+	}
+	for input, expected := range tests {
+		scn := New(input)
+		tok := scn.Next()
+		for tok.Type != token.Illegal && tok.Type != token.EOF {
+			tok = scn.Next()
+		}
+		if !reflect.DeepEqual(tok, expected) {
+			t.Errorf("%q: expected %v at %s, got %v at %s", input, expected, expected.Pos, tok, tok.Pos)
+		}
+	}
+}
+
+func TestScanner_Next_StringEscapes(t *testing.T) {
+	do(t, `"a\n\t\"b\"\\ \u{3bb}\u{1F600}"`, []token.Token{
+		token.New(token.String, pos(0), "a\n\t\"b\"\\ λ😀"),
+	})
+}
+
+func TestScanner_Next_RawString(t *testing.T) {
+	do(t, "\"\"\"a \\n \"b\"\nc\"\"\" d", []token.Token{
+		token.New(token.String, pos(0), "a \\n \"b\"\nc"),
+		token.New(token.Identifier, token.Position{Offset: 17, Line: 2, Column: 6}, "d"),
+	})
+}
+
+func TestScanner_Next_IllegalStrings(t *testing.T) {
+	tests := []struct {
+		input string
+		tok   token.Token
+	}{
+		{`(print "abc`, token.New(token.Illegal, pos(7), `"`)},
+		{`(print """abc")`, token.New(token.Illegal, pos(7), `"""`)},
+		{`(print "a\qb\u{zz}")`, token.New(token.Illegal, pos(9), `\q`)},
+		{`(print "a\u{110000}")`, token.New(token.Illegal, pos(9), `\u{110000}`)},
+		{`(print "a\`, token.New(token.Illegal, pos(7), `"`)},
+	}
+
+	for _, test := range tests {
+		scn := New(test.input)
+		scn.Next()
+		scn.Next()
+		if tok := scn.Next(); !reflect.DeepEqual(tok, test.tok) {
+			t.Errorf("%s: expected %v, got %v", test.input, test.tok, tok)
+		}
+	}
+}
+
+func TestScanner_Next_Runes(t *testing.T) {
+	do(t, `(#\a #\( #\λ #\newline #\u{41} #\space)`, []token.Token{
+		token.New(token.ParenOp, pos(0)),
+		token.New(token.Rune, pos(1), "a"),
+		token.New(token.Rune, pos(5), "("),
+		token.New(token.Rune, pos(9), "λ"),
+		token.New(token.Rune, pos(13), "\n"),
+		token.New(token.Rune, pos(23), "A"),
+		token.New(token.Rune, pos(31), " "),
+		token.New(token.ParenCl, pos(38)),
+	})
+	do(t, `#\nope #\`, []token.Token{
+		token.New(token.Illegal, pos(0), `#\nope`),
+		token.New(token.Illegal, pos(7), `#\`),
+	})
+}
+
+func TestScanner_Next_Numbers(t *testing.T) {
+	tests := map[string]token.Type{
+		"42":        token.Integer,
+		"-5":        token.Integer,
+		"+5":        token.Integer,
+		"1_000_000": token.Integer,
+		"0xff":      token.Integer,
+		"-0XFF":     token.Integer,
+		"0o17":      token.Integer,
+		"0b1010":    token.Integer,
+		"0b1_010":   token.Integer,
+		"1.5":       token.Float,
+		"-0.5":      token.Float,
+		"1e9":       token.Float,
+		"2.5E-3":    token.Float,
+		"1_0.0_1e1": token.Float,
+		"1__0":      token.Illegal,
+		"1_":        token.Illegal,
+		"0x":        token.Illegal,
+		"0b102":     token.Illegal,
+		"0xfg":      token.Illegal,
+		"1e":        token.Illegal,
+		"1.":        token.Illegal,
+		"1.2.3":     token.Illegal,
+		"12ab":      token.Illegal,
+		"0x1.5":     token.Illegal,
+	}
+
+	for input, typ := range tests {
+		expected := token.New(typ, pos(0), input)
+		if tok := New(input).Next(); !reflect.DeepEqual(tok, expected) {
+			t.Errorf("%s: expected %v, got %v", input, expected, tok)
+		}
+	}
+}
+
+func TestScanner_Next_MinusIsNotAlwaysNumber(t *testing.T) {
+	do(t, "(- -5 3)(-x)", []token.Token{
+		token.New(token.ParenOp, pos(0)),
+		token.New(token.Identifier, pos(1), "-"),
+		token.New(token.Integer, pos(3), "-5"),
+		token.New(token.Integer, pos(6), "3"),
+		token.New(token.ParenCl, pos(7)),
+		token.New(token.ParenOp, pos(8)),
+		token.New(token.Identifier, pos(9), "-x"),
+		token.New(token.ParenCl, pos(11)),
+	})
+}
+
+func TestScanner_Next_Identifiers(t *testing.T) {
+	for _, input := range []string{
+		"vec3", "empty?", "set!", "x->y", "%tmp", "a.b", "_", "snake_case",
+		"-", "+", "-x", "+inf", "->", "...", "$x", "a1b2", "λ", "<=", "/=",
+	} {
+		expected := token.New(token.Identifier, pos(0), input)
+		if tok := New(input).Next(); !reflect.DeepEqual(tok, expected) {
+			t.Errorf("%s: expected %v, got %v", input, expected, tok)
+		}
+	}
+}
+
+func TestScanner_Next_IdentifiersAndNumbers(t *testing.T) {
+	do(t, "(x-1 -1 - 1 3d)", []token.Token{
+		token.New(token.ParenOp, pos(0)),
+		token.New(token.Identifier, pos(1), "x-1"),
+		token.New(token.Integer, pos(5), "-1"),
+		token.New(token.Identifier, pos(8), "-"),
+		token.New(token.Integer, pos(10), "1"),
+		token.New(token.Illegal, pos(12), "3d"),
+		token.New(token.ParenCl, pos(14)),
+	})
+}
+
+func TestScanner_Next_Keywords(t *testing.T) {
+	do(t, "(:name :empty? : x)", []token.Token{
+		token.New(token.ParenOp, pos(0)),
+		token.New(token.Keyword, pos(1), "name"),
+		token.New(token.Keyword, pos(7), "empty?"),
+		token.New(token.Colon, pos(15)),
+		token.New(token.Identifier, pos(17), "x"),
+		token.New(token.ParenCl, pos(18)),
+	})
+}
+
+func TestScanner_Next_Braces(t *testing.T) {
+	do(t, "{:a 1}", []token.Token{
+		token.New(token.BraceOp, pos(0)),
+		token.New(token.Keyword, pos(1), "a"),
+		token.New(token.Integer, pos(4), "1"),
+		token.New(token.BraceCl, pos(5)),
+	})
+}
@@ -1,22 +1,33 @@
 package token
 
+import (
+	"fmt"
+)
+
 // Type is type of a single token
 type Type int8
 
 const (
-	EOF         Type = iota
+	EOF Type = iota
 	Illegal
 	ParenOp
 	ParenCl
 	BracketOp
 	BracketCl
+	BraceOp
+	BraceCl
 	SingleQuote
+	Backquote
+	Comma
+	CommaAt
 	Colon
 	Identifier
 	Float
 	Integer
 	Rune
 	String
+	Comment
+	Keyword
 )
 
 var type2name = map[Type]string{
@@ -26,13 +37,20 @@ var type2name = map[Type]string{
 	ParenCl:     "ParenCl<)>",
 	BracketOp:   "BracketOp<[>",
 	BracketCl:   "BracketCl<]>",
+	BraceOp:     "BraceOp<{>",
+	BraceCl:     "BraceCl<}>",
 	SingleQuote: "SingleQuote<'>",
+	Backquote:   "Backquote<`>",
+	Comma:       "Comma<,>",
+	CommaAt:     "CommaAt<,@>",
 	Colon:       "Colon<:>",
 	Identifier:  "Identifier",
 	Float:       "Float",
 	Integer:     "Integer",
 	Rune:        "Rune",
 	String:      "String",
+	Comment:     "Comment",
+	Keyword:     "Keyword",
 }
 
 func (t Type) String() string {
@@ -44,19 +62,52 @@ var defaultLiteral = map[Type]string{
 	ParenCl:     ")",
 	BracketOp:   "[",
 	BracketCl:   "]",
+	BraceOp:     "{",
+	BraceCl:     "}",
 	Colon:       ":",
 	SingleQuote: "'",
+	Backquote:   "`",
+	Comma:       ",",
+	CommaAt:     ",@",
+}
+
+// Position is a place in source code, Line and Column start with 1
+// and Offset is counted in runes from the beginning of the source
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+// IsValid tells if the position points to some real place in source
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String renders the position as file:line:column, file is omitted if empty
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
 }
 
 // Token represents a single token both terminals and non-terminals
 type Token struct {
 	Type    Type
 	Literal string
-	Pos     int
+	Pos     Position
 }
 
 // New constructs a token
-func New(typ Type, pos int, lit ...string) Token {
+func New(typ Type, pos Position, lit ...string) Token {
 	defOrLit, ok := defaultLiteral[typ]
 	if len(lit) == 0 && !ok {
 		panic("non-passing a literal for a token with no default literal")